@@ -0,0 +1,254 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	nebula "github.com/vesoft-inc/nebula-go/v3"
+	"github.com/xfali/lean/connection"
+	"github.com/xfali/lean/drivers/nebuladrv"
+	"github.com/xfali/lean/drivers/sqldrv"
+	"github.com/xfali/lean/handler"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Build creates connections of all datasources by name, connections are not opened.
+func (c *Config) Build() (map[string]connection.Connection, error) {
+	ret := make(map[string]connection.Connection, len(c.DataSources))
+	for name, ds := range c.DataSources {
+		if ds == nil {
+			return nil, fmt.Errorf("Datasource %s is empty ", name)
+		}
+		conn, err := ds.Build()
+		if err != nil {
+			return nil, fmt.Errorf("Build datasource %s failed: %v ", name, err)
+		}
+		ret[name] = conn
+	}
+	return ret, nil
+}
+
+// BuildManager creates a connection.Manager with all datasources, connections are not opened.
+func (c *Config) BuildManager() (*connection.Manager, error) {
+	conns, err := c.Build()
+	if err != nil {
+		return nil, err
+	}
+	return connection.NewManager(connection.ManagerOpts.AddConnections(conns)), nil
+}
+
+func (ds *DataSource) Build() (connection.Connection, error) {
+	switch ds.Driver {
+	case "":
+		return nil, fmt.Errorf("Driver is empty ")
+	case DriverNebula:
+		return ds.buildNebula()
+	default:
+		return ds.buildSql()
+	}
+}
+
+func (ds *DataSource) buildSql() (connection.Connection, error) {
+	dsn, err := ds.DataSourceName()
+	if err != nil {
+		return nil, err
+	}
+	opts := []sqldrv.ConnOpt{
+		sqldrv.ConnOpts.SetMaxConn(ds.Pool.MaxConn),
+		sqldrv.ConnOpts.SetMaxIdleConn(ds.Pool.MaxIdleConn),
+		sqldrv.ConnOpts.SetConnMaxIdleTime(time.Duration(ds.Pool.ConnMaxIdleTime)),
+		sqldrv.ConnOpts.SetConnMaxLifetime(time.Duration(ds.Pool.ConnMaxLifetime)),
+		sqldrv.ConnOpts.SetStatementTimeout(time.Duration(ds.StatementTimeout)),
+	}
+	switch ds.Executor {
+	case "", ExecutorSimple:
+	case ExecutorPrepare:
+		if ds.StatementCacheSize > 0 {
+			opts = append(opts, sqldrv.ConnOpts.SetStatementPool(handler.NewLRUPool(ds.StatementCacheSize)))
+		} else {
+			opts = append(opts, sqldrv.ConnOpts.SetExecutorType(sqldrv.ExecutorPrepare))
+		}
+	default:
+		return nil, fmt.Errorf("Executor type %s not support ", ds.Executor)
+	}
+	return sqldrv.NewSqlConnection(ds.Driver, dsn, opts...), nil
+}
+
+// DataSourceName returns DSN if it is not empty, otherwise builds it for mysql, postgres and sqlite drivers.
+// TLS of sql drivers is enabled by params, e.g. tls=true for mysql and sslmode=verify-full for postgres.
+func (ds *DataSource) DataSourceName() (string, error) {
+	if ds.DSN != "" {
+		return ds.DSN, nil
+	}
+	params := url.Values{}
+	for k, v := range ds.Params {
+		params.Set(k, v)
+	}
+	switch ds.Driver {
+	case "mysql":
+		if ds.TLS.Enable && params.Get("tls") == "" {
+			if ds.TLS.InsecureSkipVerify {
+				params.Set("tls", "skip-verify")
+			} else {
+				params.Set("tls", "true")
+			}
+		}
+		var sb strings.Builder
+		if ds.Username != "" {
+			sb.WriteString(ds.Username)
+			if ds.Password != "" {
+				sb.WriteString(":")
+				sb.WriteString(ds.Password)
+			}
+			sb.WriteString("@")
+		}
+		sb.WriteString("tcp(")
+		sb.WriteString(hostPort(ds.Host, ds.Port, 3306))
+		sb.WriteString(")/")
+		sb.WriteString(ds.Database)
+		if len(params) > 0 {
+			sb.WriteString("?")
+			sb.WriteString(params.Encode())
+		}
+		return sb.String(), nil
+	case "postgres", "pgx":
+		if params.Get("sslmode") == "" {
+			switch {
+			case !ds.TLS.Enable:
+				params.Set("sslmode", "disable")
+			case ds.TLS.InsecureSkipVerify:
+				params.Set("sslmode", "require")
+			default:
+				params.Set("sslmode", "verify-full")
+			}
+			if ds.TLS.CAFile != "" {
+				params.Set("sslrootcert", ds.TLS.CAFile)
+			}
+			if ds.TLS.CertFile != "" {
+				params.Set("sslcert", ds.TLS.CertFile)
+				params.Set("sslkey", ds.TLS.KeyFile)
+			}
+		}
+		u := url.URL{
+			Scheme:   "postgres",
+			Host:     hostPort(ds.Host, ds.Port, 5432),
+			Path:     "/" + ds.Database,
+			RawQuery: params.Encode(),
+		}
+		if ds.Username != "" {
+			u.User = url.UserPassword(ds.Username, ds.Password)
+		}
+		return u.String(), nil
+	case "sqlite", "sqlite3":
+		if ds.Database == "" {
+			return "", fmt.Errorf("Database of sqlite is empty ")
+		}
+		if len(params) > 0 {
+			return "file:" + ds.Database + "?" + params.Encode(), nil
+		}
+		return ds.Database, nil
+	}
+	return "", fmt.Errorf("DSN of driver %s is empty ", ds.Driver)
+}
+
+func hostPort(host string, port, defaultPort int) string {
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if port == 0 {
+		port = defaultPort
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+func (ds *DataSource) buildNebula() (connection.Connection, error) {
+	if len(ds.Addresses) == 0 {
+		return nil, fmt.Errorf("Nebula addresses are empty ")
+	}
+	opts := []nebuladrv.ConnectionOpt{
+		nebuladrv.ConnOpts.WithUserInfo(ds.Username, ds.Password),
+	}
+	for _, addr := range ds.Addresses {
+		host, port, err := net.SplitHostPort(strings.TrimSpace(addr))
+		if err != nil {
+			return nil, fmt.Errorf("Nebula address %s invalid: %v ", addr, err)
+		}
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("Nebula address %s invalid: %v ", addr, err)
+		}
+		opts = append(opts, nebuladrv.ConnOpts.AddAddress(host, p))
+	}
+
+	conf := nebula.GetDefaultConf()
+	if ds.Pool.MaxConn > 0 {
+		conf.MaxConnPoolSize = ds.Pool.MaxConn
+	}
+	if ds.Pool.MinConn > 0 {
+		conf.MinConnPoolSize = ds.Pool.MinConn
+	}
+	if ds.Pool.ConnMaxIdleTime > 0 {
+		conf.IdleTime = time.Duration(ds.Pool.ConnMaxIdleTime)
+	}
+	if ds.Pool.Timeout > 0 {
+		conf.TimeOut = time.Duration(ds.Pool.Timeout)
+	}
+	opts = append(opts, nebuladrv.ConnOpts.SetConnectConfig(conf), nebuladrv.ConnOpts.SetStatementTimeout(time.Duration(ds.StatementTimeout)))
+
+	if ds.TLS.Enable {
+		tlsConf, err := ds.TLS.Config()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, nebuladrv.ConnOpts.SetSslConfig(tlsConf))
+	}
+	return nebuladrv.NewNebulaConnection(opts...), nil
+}
+
+// Config creates tls.Config from the files.
+func (t TLS) Config() (*tls.Config, error) {
+	ret := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	if t.CAFile != "" {
+		data, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Read CA file failed: %v ", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("CA file %s has no certificate ", t.CAFile)
+		}
+		ret.RootCAs = pool
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Load key pair failed: %v ", err)
+		}
+		ret.Certificates = []tls.Certificate{cert}
+	}
+	return ret, nil
+}
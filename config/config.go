@@ -0,0 +1,193 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+)
+
+const (
+	ExecutorSimple  = "simple"
+	ExecutorPrepare = "prepare"
+
+	DriverNebula = "nebula"
+)
+
+// Config is the root of configuration file:
+//
+//	datasources:
+//	  orders:
+//	    driver: mysql
+//	    host: 127.0.0.1
+//	    port: 3306
+//	    database: orders
+//	    username: root
+//	    password: ${MYSQL_PASSWORD}
+//	    executor: prepare
+//	    pool:
+//	      maxConn: 20
+//	      connMaxLifetime: 5m
+//	  graph:
+//	    driver: nebula
+//	    addresses: [127.0.0.1:9669]
+//	    username: root
+//	    password: nebula
+type Config struct {
+	DataSources map[string]*DataSource `yaml:"datasources" json:"datasources"`
+}
+
+type DataSource struct {
+	// Driver is the name of database/sql driver or "nebula".
+	Driver string `yaml:"driver" json:"driver"`
+	// DSN is used as is if not empty, otherwise it is built from Host, Port, Database, Username, Password and Params.
+	DSN      string            `yaml:"dsn" json:"dsn"`
+	Host     string            `yaml:"host" json:"host"`
+	Port     int               `yaml:"port" json:"port"`
+	Database string            `yaml:"database" json:"database"`
+	Username string            `yaml:"username" json:"username"`
+	Password string            `yaml:"password" json:"password"`
+	Params   map[string]string `yaml:"params" json:"params"`
+
+	// Addresses are host:port of nebula graphd.
+	Addresses []string `yaml:"addresses" json:"addresses"`
+
+	// Executor is "simple" or "prepare".
+	Executor string `yaml:"executor" json:"executor"`
+	// StatementCacheSize is the capacity of statement pool of prepare executor.
+	StatementCacheSize int `yaml:"statementCacheSize" json:"statementCacheSize"`
+	// StatementTimeout is the default timeout of statements, 0 means no timeout.
+	StatementTimeout Duration `yaml:"statementTimeout" json:"statementTimeout"`
+
+	Pool Pool `yaml:"pool" json:"pool"`
+	TLS  TLS  `yaml:"tls" json:"tls"`
+}
+
+type Pool struct {
+	MaxConn         int      `yaml:"maxConn" json:"maxConn"`
+	MinConn         int      `yaml:"minConn" json:"minConn"`
+	MaxIdleConn     int      `yaml:"maxIdleConn" json:"maxIdleConn"`
+	ConnMaxIdleTime Duration `yaml:"connMaxIdleTime" json:"connMaxIdleTime"`
+	ConnMaxLifetime Duration `yaml:"connMaxLifetime" json:"connMaxLifetime"`
+	// Timeout is the socket timeout of nebula connections.
+	Timeout Duration `yaml:"timeout" json:"timeout"`
+}
+
+type TLS struct {
+	Enable             bool   `yaml:"enable" json:"enable"`
+	CAFile             string `yaml:"caFile" json:"caFile"`
+	CertFile           string `yaml:"certFile" json:"certFile"`
+	KeyFile            string `yaml:"keyFile" json:"keyFile"`
+	ServerName         string `yaml:"serverName" json:"serverName"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify" json:"insecureSkipVerify"`
+}
+
+// Duration is time.Duration which is read from string like "5s" or integer of nanoseconds.
+type Duration time.Duration
+
+func (d *Duration) set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return d.set(s)
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("Duration expect string or integer but get %s ", string(data))
+	}
+	*d = Duration(n)
+	return nil
+}
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var n int64
+	if err := value.Decode(&n); err == nil {
+		*d = Duration(n)
+		return nil
+	}
+	return d.set(value.Value)
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// Load reads configuration file, the format is decided by extension (.yaml, .yml or .json).
+// ${VAR} in file is expanded by environment variables, other $ are kept as they are.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := FormatYAML
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = FormatJSON
+	}
+	return Parse(data, format)
+}
+
+var envRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${VAR} with the value of environment variable, unset variables are replaced with empty string.
+func expandEnv(data []byte) []byte {
+	return envRegexp.ReplaceAllFunc(data, func(s []byte) []byte {
+		return []byte(os.Getenv(string(s[2 : len(s)-1])))
+	})
+}
+
+func Parse(data []byte, format Format) (*Config, error) {
+	data = expandEnv(data)
+	ret := &Config{}
+	var err error
+	switch format {
+	case FormatJSON:
+		err = json.Unmarshal(data, ret)
+	case FormatYAML:
+		err = yaml.Unmarshal(data, ret)
+	default:
+		return nil, fmt.Errorf("Config format %s not support ", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Parse config failed: %v ", err)
+	}
+	return ret, nil
+}
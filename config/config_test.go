@@ -0,0 +1,140 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+const testYaml = `
+datasources:
+  orders:
+    driver: mysql
+    host: db.local
+    database: orders
+    username: root
+    password: ${LEAN_TEST_PASSWORD}
+    executor: prepare
+    statementCacheSize: 16
+    params:
+      parseTime: "true"
+    pool:
+      maxConn: 20
+      connMaxLifetime: 5m
+  graph:
+    driver: nebula
+    addresses: [127.0.0.1:9669, 127.0.0.2:9669]
+    username: root
+    password: nebula
+    pool:
+      timeout: 3s
+`
+
+func TestParse(t *testing.T) {
+	t.Setenv("LEAN_TEST_PASSWORD", "secret")
+	conf, err := Parse([]byte(testYaml), FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders := conf.DataSources["orders"]
+	if orders.Password != "secret" || orders.Pool.MaxConn != 20 || time.Duration(orders.Pool.ConnMaxLifetime) != 5*time.Minute {
+		t.Fatal("unexpected orders ", orders)
+	}
+	dsn, err := orders.DataSourceName()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsn != "root:secret@tcp(db.local:3306)/orders?parseTime=true" {
+		t.Fatal(dsn)
+	}
+	if graph := conf.DataSources["graph"]; len(graph.Addresses) != 2 || time.Duration(graph.Pool.Timeout) != 3*time.Second {
+		t.Fatal("unexpected graph ", graph)
+	}
+
+	conns, err := conf.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conns) != 2 {
+		t.Fatal("expect 2 connections but get ", len(conns))
+	}
+}
+
+func TestParseDollar(t *testing.T) {
+	t.Setenv("LEAN_TEST_USER", "admin")
+	conf, err := Parse([]byte(`{"datasources": {"users": {"driver": "postgres", "host": "pg",
+		"username": "${LEAN_TEST_USER}", "password": "pa$$word$def$"}}}`), FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users := conf.DataSources["users"]; users.Username != "admin" || users.Password != "pa$$word$def$" {
+		t.Fatal("unexpected user info ", users.Username, users.Password)
+	}
+}
+
+func TestParseJson(t *testing.T) {
+	conf, err := Parse([]byte(`{"datasources": {"users": {"driver": "postgres", "host": "pg", "port": 5433,
+		"database": "users", "username": "u", "password": "p", "tls": {"enable": true},
+		"pool": {"connMaxIdleTime": "1m"}}}}`), FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := conf.DataSources["users"]
+	if time.Duration(users.Pool.ConnMaxIdleTime) != time.Minute {
+		t.Fatal("unexpected idle time ", users.Pool.ConnMaxIdleTime)
+	}
+	dsn, err := users.DataSourceName()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsn != "postgres://u:p@pg:5433/users?sslmode=verify-full" {
+		t.Fatal(dsn)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	conf := &Config{DataSources: map[string]*DataSource{
+		"orders": {Driver: "mysql"},
+	}}
+	err := conf.applyEnv("lean", []string{
+		"LEAN_ORDERS_DSN=root@tcp(127.0.0.1)/orders",
+		"LEAN_ORDERS_POOL_MAX_IDLE_CONN=4",
+		"LEAN_ORDERS_POOL_MAX_CONN=8",
+		"LEAN_ORDERS_POOL_CONN_MAX_LIFETIME=1h",
+		"LEAN_GRAPH_DRIVER=nebula",
+		"LEAN_GRAPH_ADDRESSES=h1:9669,h2:9669",
+		"LEAN_GRAPH_TLS_ENABLE=true",
+		"OTHER_ORDERS_DSN=x",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders := conf.DataSources["orders"]
+	if orders.DSN != "root@tcp(127.0.0.1)/orders" || orders.Pool.MaxIdleConn != 4 || orders.Pool.MaxConn != 8 ||
+		time.Duration(orders.Pool.ConnMaxLifetime) != time.Hour {
+		t.Fatal("unexpected orders ", orders)
+	}
+	graph := conf.DataSources["graph"]
+	if graph == nil || graph.Driver != "nebula" || len(graph.Addresses) != 2 || !graph.TLS.Enable {
+		t.Fatal("unexpected graph ", graph)
+	}
+
+	if err := conf.applyEnv("lean", []string{"LEAN_ORDERS_PORT=x"}); err == nil {
+		t.Fatal("expect invalid port error")
+	}
+}
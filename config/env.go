@@ -0,0 +1,143 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type envSetter func(ds *DataSource, v string) error
+
+func setInt(p *int) envSetter {
+	return func(ds *DataSource, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*p = n
+		return nil
+	}
+}
+
+// envKeys returns the setters of environment variables by key.
+func envKeys(ds *DataSource) map[string]envSetter {
+	str := func(p *string) envSetter {
+		return func(ds *DataSource, v string) error {
+			*p = v
+			return nil
+		}
+	}
+	boolean := func(p *bool) envSetter {
+		return func(ds *DataSource, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return err
+			}
+			*p = b
+			return nil
+		}
+	}
+	return map[string]envSetter{
+		"DRIVER":   str(&ds.Driver),
+		"DSN":      str(&ds.DSN),
+		"HOST":     str(&ds.Host),
+		"PORT":     setInt(&ds.Port),
+		"DATABASE": str(&ds.Database),
+		"USERNAME": str(&ds.Username),
+		"PASSWORD": str(&ds.Password),
+		"ADDRESSES": func(ds *DataSource, v string) error {
+			ds.Addresses = strings.Split(v, ",")
+			return nil
+		},
+		"EXECUTOR":                 str(&ds.Executor),
+		"STATEMENT_CACHE_SIZE":     setInt(&ds.StatementCacheSize),
+		"STATEMENT_TIMEOUT":        func(ds *DataSource, v string) error { return ds.StatementTimeout.set(v) },
+		"POOL_MAX_CONN":            setInt(&ds.Pool.MaxConn),
+		"POOL_MIN_CONN":            setInt(&ds.Pool.MinConn),
+		"POOL_MAX_IDLE_CONN":       setInt(&ds.Pool.MaxIdleConn),
+		"POOL_CONN_MAX_IDLE_TIME":  func(ds *DataSource, v string) error { return ds.Pool.ConnMaxIdleTime.set(v) },
+		"POOL_CONN_MAX_LIFETIME":   func(ds *DataSource, v string) error { return ds.Pool.ConnMaxLifetime.set(v) },
+		"POOL_TIMEOUT":             func(ds *DataSource, v string) error { return ds.Pool.Timeout.set(v) },
+		"TLS_ENABLE":               boolean(&ds.TLS.Enable),
+		"TLS_CA_FILE":              str(&ds.TLS.CAFile),
+		"TLS_CERT_FILE":            str(&ds.TLS.CertFile),
+		"TLS_KEY_FILE":             str(&ds.TLS.KeyFile),
+		"TLS_SERVER_NAME":          str(&ds.TLS.ServerName),
+		"TLS_INSECURE_SKIP_VERIFY": boolean(&ds.TLS.InsecureSkipVerify),
+	}
+}
+
+var envKeyNames = func() []string {
+	var ret []string
+	for k := range envKeys(&DataSource{}) {
+		ret = append(ret, k)
+	}
+	// match the longest key first, e.g. POOL_MAX_IDLE_CONN before POOL_MAX_CONN
+	sort.Slice(ret, func(i, j int) bool { return len(ret[i]) > len(ret[j]) })
+	return ret
+}()
+
+// ApplyEnv overrides configuration by environment variables named <PREFIX>_<DATASOURCE>_<KEY>, e.g.
+// LEAN_ORDERS_PASSWORD or LEAN_ORDERS_POOL_MAX_CONN. Datasource name is matched case-insensitively,
+// a new datasource is added if not found.
+func (c *Config) ApplyEnv(prefix string) error {
+	return c.applyEnv(prefix, os.Environ())
+}
+
+func (c *Config) applyEnv(prefix string, environ []string) error {
+	prefix = strings.ToUpper(prefix) + "_"
+	for _, kv := range environ {
+		i := strings.Index(kv, "=")
+		if i < 0 || !strings.HasPrefix(kv[:i], prefix) {
+			continue
+		}
+		rest, value := kv[len(prefix):i], kv[i+1:]
+		for _, key := range envKeyNames {
+			if !strings.HasSuffix(rest, "_"+key) {
+				continue
+			}
+			ds := c.dataSource(rest[:len(rest)-len(key)-1])
+			if err := envKeys(ds)[key](ds, value); err != nil {
+				return fmt.Errorf("Env %s invalid: %v ", kv[:i], err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func (c *Config) dataSource(name string) *DataSource {
+	for k, v := range c.DataSources {
+		if strings.EqualFold(k, name) {
+			if v == nil {
+				v = &DataSource{}
+				c.DataSources[k] = v
+			}
+			return v
+		}
+	}
+	if c.DataSources == nil {
+		c.DataSources = map[string]*DataSource{}
+	}
+	ret := &DataSource{}
+	c.DataSources[strings.ToLower(name)] = ret
+	return ret
+}
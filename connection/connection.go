@@ -16,10 +16,62 @@
 
 package connection
 
-import "github.com/xfali/lean/session"
+import (
+	"github.com/xfali/lean/session"
+	"github.com/xfali/lean/transaction"
+	"time"
+)
 
 type Connection interface {
 	Open() error
 	GetSession() (session.Session, error)
 	Close() error
 }
+
+// PoolStats is the statistics of connection pool, fields not supported by the backend are zero.
+type PoolStats struct {
+	// Up is false if the backend is unavailable.
+	Up                bool
+	MaxOpen           int
+	Open              int
+	InUse             int
+	Idle              int
+	WaitCount         int64
+	WaitDuration      time.Duration
+	MaxIdleClosed     int64
+	MaxLifetimeClosed int64
+}
+
+// StatsProvider is implemented by connections which expose statistics of pool.
+type StatsProvider interface {
+	PoolStats() PoolStats
+}
+
+// Merge returns the sum of statistics of two pools, Up is true if both are up.
+func (s PoolStats) Merge(o PoolStats) PoolStats {
+	return PoolStats{
+		Up:                s.Up && o.Up,
+		MaxOpen:           s.MaxOpen + o.MaxOpen,
+		Open:              s.Open + o.Open,
+		InUse:             s.InUse + o.InUse,
+		Idle:              s.Idle + o.Idle,
+		WaitCount:         s.WaitCount + o.WaitCount,
+		WaitDuration:      s.WaitDuration + o.WaitDuration,
+		MaxIdleClosed:     s.MaxIdleClosed + o.MaxIdleClosed,
+		MaxLifetimeClosed: s.MaxLifetimeClosed + o.MaxLifetimeClosed,
+	}
+}
+
+// GetPoolStats returns the statistics of conn, false if conn does not implement StatsProvider.
+func GetPoolStats(conn Connection) (PoolStats, bool) {
+	if p, ok := conn.(StatsProvider); ok {
+		return p.PoolStats(), true
+	}
+	return PoolStats{}, false
+}
+
+// TransactionWrapping is implemented by connections whose sessions are built on transaction.Transaction.
+type TransactionWrapping interface {
+	// GetWrappedSession returns a session whose transaction is wrapped by wrapper.
+	GetWrappedSession(wrapper transaction.Wrapper) (session.Session, error)
+}
@@ -0,0 +1,215 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package connection
+
+import (
+	"context"
+	"fmt"
+	"github.com/xfali/lean/errors"
+	"github.com/xfali/lean/session"
+	"sort"
+	"sync"
+)
+
+// Pinger is implemented by connections which can check the health of backends.
+type Pinger interface {
+	Ping(ctx context.Context) bool
+}
+
+type ManagerOpt func(*Manager)
+
+// Manager holds named connections, opens and closes them together.
+type Manager struct {
+	locker sync.RWMutex
+	names  []string
+	conns  map[string]Connection
+	closed bool
+
+	inflight sync.WaitGroup
+}
+
+func NewManager(opts ...ManagerOpt) *Manager {
+	ret := &Manager{
+		conns: map[string]Connection{},
+	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
+}
+
+// Register adds a named connection, it is opened by Open, register after Open must open the connection by caller.
+func (m *Manager) Register(name string, conn Connection) error {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+
+	if _, ok := m.conns[name]; ok {
+		return fmt.Errorf("Connection %s has been registered ", name)
+	}
+	m.conns[name] = conn
+	m.names = append(m.names, name)
+	return nil
+}
+
+func (m *Manager) Get(name string) (Connection, bool) {
+	m.locker.RLock()
+	defer m.locker.RUnlock()
+
+	v, ok := m.conns[name]
+	return v, ok
+}
+
+// Names returns the names of connections in the order of registration.
+func (m *Manager) Names() []string {
+	m.locker.RLock()
+	defer m.locker.RUnlock()
+
+	return append([]string(nil), m.names...)
+}
+
+// Open opens all connections, the opened connections are closed if one failed.
+func (m *Manager) Open() error {
+	m.locker.RLock()
+	defer m.locker.RUnlock()
+
+	for i, name := range m.names {
+		if err := m.conns[name].Open(); err != nil {
+			for _, opened := range m.names[:i] {
+				_ = m.conns[opened].Close()
+			}
+			return fmt.Errorf("Open connection %s failed: %w ", name, err)
+		}
+	}
+	return nil
+}
+
+// GetSession returns a session of the named connection, Close waits until it is closed.
+func (m *Manager) GetSession(name string) (session.Session, error) {
+	m.locker.RLock()
+	defer m.locker.RUnlock()
+
+	if m.closed {
+		return nil, errors.ConnectionClosed
+	}
+	conn, ok := m.conns[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s ", errors.ConnectionNotFound, name)
+	}
+	sess, err := conn.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	m.inflight.Add(1)
+	return &managedSession{Session: sess, done: m.inflight.Done}, nil
+}
+
+// Ping returns the health of connections by name. Connections implement Pinger are checked by it,
+// others are checked by Ping of a new session.
+func (m *Manager) Ping(ctx context.Context) map[string]bool {
+	m.locker.RLock()
+	defer m.locker.RUnlock()
+
+	ret := make(map[string]bool, len(m.conns))
+	for name, conn := range m.conns {
+		ret[name] = ping(ctx, conn)
+	}
+	return ret
+}
+
+func ping(ctx context.Context, conn Connection) bool {
+	if p, ok := conn.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	sess, err := conn.GetSession()
+	if err != nil {
+		return false
+	}
+	defer sess.Close()
+	return sess.Ping(ctx)
+}
+
+// Close stops handing out sessions and waits until the in-flight sessions are closed or ctx is done,
+// then closes all connections in the reverse order of registration.
+func (m *Manager) Close(ctx context.Context) error {
+	m.locker.Lock()
+	if m.closed {
+		m.locker.Unlock()
+		return nil
+	}
+	m.closed = true
+	m.locker.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		m.inflight.Wait()
+		close(drained)
+	}()
+	var ret error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		ret = fmt.Errorf("Drain sessions failed: %w ", ctx.Err())
+	}
+
+	m.locker.RLock()
+	defer m.locker.RUnlock()
+	for i := len(m.names) - 1; i >= 0; i-- {
+		if err := m.conns[m.names[i]].Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+type managedSession struct {
+	session.Session
+	once sync.Once
+	done func()
+}
+
+func (s *managedSession) Close() error {
+	err := s.Session.Close()
+	s.once.Do(s.done)
+	return err
+}
+
+type managerOpts struct{}
+
+var ManagerOpts managerOpts
+
+func (o managerOpts) AddConnection(name string, conn Connection) ManagerOpt {
+	return func(m *Manager) {
+		if _, ok := m.conns[name]; !ok {
+			m.names = append(m.names, name)
+		}
+		m.conns[name] = conn
+	}
+}
+
+// AddConnections adds connections by name, they are registered in the order of names.
+func (o managerOpts) AddConnections(conns map[string]Connection) ManagerOpt {
+	return func(m *Manager) {
+		names := make([]string, 0, len(conns))
+		for name := range conns {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			o.AddConnection(name, conns[name])(m)
+		}
+	}
+}
@@ -0,0 +1,428 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package connection
+
+import (
+	"context"
+	"errors"
+	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/session"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Balancer int
+
+const (
+	// BalanceRoundRobin routes queries to the healthy replicas in turn.
+	BalanceRoundRobin Balancer = iota
+	// BalanceLeastLatency routes queries to the healthy replica with the lowest latency.
+	BalanceLeastLatency
+)
+
+const (
+	DefaultHealthCheckInterval = 5 * time.Second
+	DefaultPingTimeout         = time.Second
+)
+
+type RWOpt func(*rwConnection)
+
+type primaryKey struct{}
+
+// WithPrimary forces queries with the returned context to be routed to the primary, e.g. read your writes.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func IsPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryKey{}).(bool)
+	return v
+}
+
+type replica struct {
+	conn    Connection
+	healthy int32
+	// nanoseconds, moving average of ping and query latency
+	latency int64
+}
+
+func (r *replica) isHealthy() bool {
+	return atomic.LoadInt32(&r.healthy) == 1
+}
+
+func (r *replica) setHealthy(healthy bool) {
+	var v int32
+	if healthy {
+		v = 1
+	}
+	atomic.StoreInt32(&r.healthy, v)
+}
+
+func (r *replica) observe(d time.Duration) {
+	for {
+		old := atomic.LoadInt64(&r.latency)
+		v := int64(d)
+		if old > 0 {
+			v = (old*4 + v) / 5
+		}
+		if atomic.CompareAndSwapInt64(&r.latency, old, v) {
+			return
+		}
+	}
+}
+
+type rwConnection struct {
+	primary  Connection
+	replicas []*replica
+
+	balancer      Balancer
+	checkInterval time.Duration
+	pingTimeout   time.Duration
+
+	counter uint64
+
+	// locker guards opened and stopC
+	locker sync.Mutex
+	opened bool
+	stopC  chan struct{}
+	wait   sync.WaitGroup
+}
+
+// NewReadWriteConnection creates a connection which routes Query outside transaction to replicas,
+// Execute and all statements inside transaction are routed to the primary.
+// If all replicas are unhealthy, queries fall back to the primary.
+func NewReadWriteConnection(primary Connection, replicas []Connection, opts ...RWOpt) *rwConnection {
+	ret := &rwConnection{
+		primary:       primary,
+		checkInterval: DefaultHealthCheckInterval,
+		pingTimeout:   DefaultPingTimeout,
+	}
+	for _, c := range replicas {
+		ret.replicas = append(ret.replicas, &replica{conn: c})
+	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
+}
+
+func (c *rwConnection) Open() error {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	if err := c.primary.Open(); err != nil {
+		return err
+	}
+	for i, r := range c.replicas {
+		if err := r.conn.Open(); err != nil {
+			for _, opened := range c.replicas[:i] {
+				_ = opened.conn.Close()
+			}
+			_ = c.primary.Close()
+			return err
+		}
+	}
+	c.opened = true
+	c.check()
+	if c.checkInterval > 0 && len(c.replicas) > 0 {
+		c.stopC = make(chan struct{})
+		c.wait.Add(1)
+		go c.loop(c.stopC)
+	}
+	return nil
+}
+
+func (c *rwConnection) loop(stopC <-chan struct{}) {
+	defer c.wait.Done()
+	ticker := time.NewTicker(c.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopC:
+			return
+		case <-ticker.C:
+			c.check()
+		}
+	}
+}
+
+// check pings all replicas and updates their health and latency.
+func (c *rwConnection) check() {
+	for _, r := range c.replicas {
+		r.setHealthy(c.ping(r))
+	}
+}
+
+func (c *rwConnection) ping(r *replica) bool {
+	sess, err := r.conn.GetSession()
+	if err != nil {
+		return false
+	}
+	defer sess.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), c.pingTimeout)
+	defer cancel()
+	now := time.Now()
+	if !sess.Ping(ctx) {
+		return false
+	}
+	r.observe(time.Since(now))
+	return true
+}
+
+// pick selects a healthy replica, returns nil if there is none.
+func (c *rwConnection) pick() *replica {
+	switch c.balancer {
+	case BalanceLeastLatency:
+		var ret *replica
+		for _, r := range c.replicas {
+			if r.isHealthy() && (ret == nil || atomic.LoadInt64(&r.latency) < atomic.LoadInt64(&ret.latency)) {
+				ret = r
+			}
+		}
+		return ret
+	default:
+		n := len(c.replicas)
+		if n == 0 {
+			return nil
+		}
+		start := int(atomic.AddUint64(&c.counter, 1) % uint64(n))
+		for i := 0; i < n; i++ {
+			if r := c.replicas[(start+i)%n]; r.isHealthy() {
+				return r
+			}
+		}
+		return nil
+	}
+}
+
+func (c *rwConnection) GetSession() (session.Session, error) {
+	c.locker.Lock()
+	opened := c.opened
+	c.locker.Unlock()
+	if !opened {
+		return nil, errors.New("Connection not opened ")
+	}
+	return &rwSession{
+		conn:     c,
+		replicas: map[*replica]session.Session{},
+	}, nil
+}
+
+// PoolStats returns the sum of statistics of the primary and replicas, Up is the state of the primary.
+func (c *rwConnection) PoolStats() PoolStats {
+	ret, _ := GetPoolStats(c.primary)
+	up := ret.Up
+	for _, r := range c.replicas {
+		if v, ok := GetPoolStats(r.conn); ok {
+			ret = ret.Merge(v)
+		}
+	}
+	ret.Up = up
+	return ret
+}
+
+func (c *rwConnection) Close() error {
+	c.locker.Lock()
+	c.opened = false
+	stopC := c.stopC
+	c.stopC = nil
+	c.locker.Unlock()
+	if stopC != nil {
+		close(stopC)
+		c.wait.Wait()
+	}
+	var ret error
+	for _, r := range c.replicas {
+		if err := r.conn.Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	if err := c.primary.Close(); err != nil && ret == nil {
+		ret = err
+	}
+	return ret
+}
+
+// Ping checks the primary and all replicas, replicas health is updated.
+func (c *rwConnection) Ping(ctx context.Context) bool {
+	c.check()
+	sess, err := c.primary.GetSession()
+	if err != nil {
+		return false
+	}
+	defer sess.Close()
+	return sess.Ping(ctx)
+}
+
+// Healthy returns the health state of replicas by index.
+func (c *rwConnection) Healthy() []bool {
+	ret := make([]bool, len(c.replicas))
+	for i, r := range c.replicas {
+		ret[i] = r.isHealthy()
+	}
+	return ret
+}
+
+// rwSession creates the sessions of primary and replicas lazily.
+type rwSession struct {
+	conn     *rwConnection
+	primary  session.Session
+	replicas map[*replica]session.Session
+	inTx     bool
+}
+
+func (s *rwSession) getPrimary() (session.Session, error) {
+	if s.primary == nil {
+		sess, err := s.conn.primary.GetSession()
+		if err != nil {
+			return nil, err
+		}
+		s.primary = sess
+	}
+	return s.primary, nil
+}
+
+func (s *rwSession) getReplica(r *replica) (session.Session, error) {
+	if sess, ok := s.replicas[r]; ok {
+		return sess, nil
+	}
+	sess, err := r.conn.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	s.replicas[r] = sess
+	return sess, nil
+}
+
+func (s *rwSession) Ping(ctx context.Context) bool {
+	sess, err := s.getPrimary()
+	if err != nil {
+		return false
+	}
+	return sess.Ping(ctx)
+}
+
+func (s *rwSession) Query(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	if !s.inTx && !IsPrimary(ctx) {
+		if r := s.conn.pick(); r != nil {
+			sess, err := s.getReplica(r)
+			if err == nil {
+				now := time.Now()
+				ret, err := sess.Query(ctx, stmt, params...)
+				if err == nil {
+					r.observe(time.Since(now))
+				}
+				return ret, err
+			}
+		}
+	}
+	sess, err := s.getPrimary()
+	if err != nil {
+		return nil, err
+	}
+	return sess.Query(ctx, stmt, params...)
+}
+
+func (s *rwSession) Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	sess, err := s.getPrimary()
+	if err != nil {
+		return nil, err
+	}
+	return sess.Execute(ctx, stmt, params...)
+}
+
+func (s *rwSession) ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error) {
+	sess, err := s.getPrimary()
+	if err != nil {
+		return nil, err
+	}
+	return sess.ExecuteBatch(ctx, stmt, params)
+}
+
+func (s *rwSession) Begin(ctx context.Context) error {
+	sess, err := s.getPrimary()
+	if err != nil {
+		return err
+	}
+	err = sess.Begin(ctx)
+	if err == nil {
+		s.inTx = true
+	}
+	return err
+}
+
+func (s *rwSession) Commit(ctx context.Context) error {
+	sess, err := s.getPrimary()
+	if err != nil {
+		return err
+	}
+	if err = sess.Commit(ctx); err == nil {
+		s.inTx = false
+	}
+	return err
+}
+
+func (s *rwSession) Rollback(ctx context.Context) error {
+	sess, err := s.getPrimary()
+	if err != nil {
+		return err
+	}
+	if err = sess.Rollback(ctx); err == nil {
+		s.inTx = false
+	}
+	return err
+}
+
+func (s *rwSession) Close() error {
+	var ret error
+	for r, sess := range s.replicas {
+		if err := sess.Close(); err != nil && ret == nil {
+			ret = err
+		}
+		delete(s.replicas, r)
+	}
+	if s.primary != nil {
+		if err := s.primary.Close(); err != nil && ret == nil {
+			ret = err
+		}
+		s.primary = nil
+	}
+	return ret
+}
+
+type rwOpts struct{}
+
+var RWOpts rwOpts
+
+func (rwOpts) SetBalancer(balancer Balancer) RWOpt {
+	return func(c *rwConnection) {
+		c.balancer = balancer
+	}
+}
+
+// SetHealthCheckInterval sets the interval of pinging replicas, 0 disables the background check.
+func (rwOpts) SetHealthCheckInterval(interval time.Duration) RWOpt {
+	return func(c *rwConnection) {
+		c.checkInterval = interval
+	}
+}
+
+func (rwOpts) SetPingTimeout(timeout time.Duration) RWOpt {
+	return func(c *rwConnection) {
+		c.pingTimeout = timeout
+	}
+}
@@ -0,0 +1,466 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package connection
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"github.com/xfali/lean/errors"
+	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/session"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ShardStrategy maps shard key to the index of shards.
+type ShardStrategy interface {
+	Shard(key interface{}, shards int) (int, error)
+}
+
+// ShardKeyExtractor extracts shard key from statement and params, returns false if not found.
+type ShardKeyExtractor func(stmt string, params ...interface{}) (interface{}, bool)
+
+type ShardOpt func(*shardConnection)
+
+type shardKey struct{}
+
+type allShardsKey struct{}
+
+// WithShardKey routes statements with the returned context to the shard of key.
+func WithShardKey(ctx context.Context, key interface{}) context.Context {
+	return context.WithValue(ctx, shardKey{}, key)
+}
+
+func GetShardKey(ctx context.Context) (interface{}, bool) {
+	v := ctx.Value(shardKey{})
+	return v, v != nil
+}
+
+// WithAllShards makes Execute without shard key run on all shards, by default it returns errors.ShardKeyNotFound.
+func WithAllShards(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allShardsKey{}, true)
+}
+
+func isAllShards(ctx context.Context) bool {
+	v, _ := ctx.Value(allShardsKey{}).(bool)
+	return v
+}
+
+type hashStrategy struct{}
+
+// HashStrategy selects shard by FNV-1a hash of the key's string form.
+func HashStrategy() ShardStrategy {
+	return hashStrategy{}
+}
+
+func (hashStrategy) Shard(key interface{}, shards int) (int, error) {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(fmt.Sprint(key)))
+	return int(h.Sum32() % uint32(shards)), nil
+}
+
+type rangeStrategy struct {
+	bounds []int64
+}
+
+// RangeStrategy selects shard by integer key, shard i holds keys less than bounds[i] and not less than bounds[i-1],
+// the last shard holds the rest. bounds must be ascending and len(bounds) must be shards - 1.
+func RangeStrategy(bounds ...int64) ShardStrategy {
+	b := append([]int64(nil), bounds...)
+	sort.Slice(b, func(i, j int) bool { return b[i] < b[j] })
+	return &rangeStrategy{bounds: b}
+}
+
+func (s *rangeStrategy) Shard(key interface{}, shards int) (int, error) {
+	if len(s.bounds) != shards-1 {
+		return 0, fmt.Errorf("%w: expect %d range bounds but get %d ", errors.ShardKeyInvalid, shards-1, len(s.bounds))
+	}
+	v, ok := toInt64(key)
+	if !ok {
+		return 0, fmt.Errorf("%w: range shard key must be integer but get %T ", errors.ShardKeyInvalid, key)
+	}
+	return sort.Search(len(s.bounds), func(i int) bool { return v < s.bounds[i] }), nil
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+type shardConnection struct {
+	shards    []Connection
+	strategy  ShardStrategy
+	extractor ShardKeyExtractor
+}
+
+// NewShardConnection creates a connection which routes statements to shards by the shard key in context
+// or extracted by ShardKeyExtractor. Queries without shard key run on all shards and the results are merged.
+// Transaction is bound to the shard of its first statement, statements of other shards are rejected.
+func NewShardConnection(shards []Connection, opts ...ShardOpt) *shardConnection {
+	ret := &shardConnection{
+		shards:   shards,
+		strategy: HashStrategy(),
+	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
+}
+
+func (c *shardConnection) Open() error {
+	for i, conn := range c.shards {
+		if err := conn.Open(); err != nil {
+			for _, opened := range c.shards[:i] {
+				_ = opened.Close()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *shardConnection) GetSession() (session.Session, error) {
+	if len(c.shards) == 0 {
+		return nil, stderrors.New("No shard ")
+	}
+	return &shardSession{
+		conn:     c,
+		sessions: make([]session.Session, len(c.shards)),
+		txShard:  -1,
+	}, nil
+}
+
+func (c *shardConnection) Close() error {
+	var ret error
+	for _, conn := range c.shards {
+		if err := conn.Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+// PoolStats returns the sum of statistics of shards, Up is true if all shards are up.
+func (c *shardConnection) PoolStats() PoolStats {
+	var ret PoolStats
+	for i, conn := range c.shards {
+		v, _ := GetPoolStats(conn)
+		if i == 0 {
+			ret = v
+		} else {
+			ret = ret.Merge(v)
+		}
+	}
+	return ret
+}
+
+// shard returns the shard index of statement, -1 if shard key not found.
+func (c *shardConnection) shard(ctx context.Context, stmt string, params ...interface{}) (int, error) {
+	key, ok := GetShardKey(ctx)
+	if !ok && c.extractor != nil {
+		key, ok = c.extractor(stmt, params...)
+	}
+	if !ok {
+		return -1, nil
+	}
+	return c.route(key)
+}
+
+func (c *shardConnection) route(key interface{}) (int, error) {
+	i, err := c.strategy.Shard(key, len(c.shards))
+	if err != nil {
+		return -1, err
+	}
+	if i < 0 || i >= len(c.shards) {
+		return -1, fmt.Errorf("%w: shard %d out of range ", errors.ShardKeyInvalid, i)
+	}
+	return i, nil
+}
+
+// shardSession creates the sessions of shards lazily.
+type shardSession struct {
+	conn     *shardConnection
+	sessions []session.Session
+
+	inTx bool
+	// -1 if transaction has not been bound to a shard
+	txShard int
+}
+
+func (s *shardSession) session(i int) (session.Session, error) {
+	if s.sessions[i] == nil {
+		sess, err := s.conn.shards[i].GetSession()
+		if err != nil {
+			return nil, err
+		}
+		s.sessions[i] = sess
+	}
+	return s.sessions[i], nil
+}
+
+// get returns the session of shard i, the transaction is begun on it if it is the first shard in transaction.
+func (s *shardSession) get(ctx context.Context, i int) (session.Session, error) {
+	if s.inTx && s.txShard >= 0 && s.txShard != i {
+		return nil, fmt.Errorf("%w: transaction on shard %d but get shard %d ", errors.ShardCrossTransaction, s.txShard, i)
+	}
+	sess, err := s.session(i)
+	if err != nil {
+		return nil, err
+	}
+	if s.inTx && s.txShard < 0 {
+		if err := sess.Begin(ctx); err != nil {
+			return nil, err
+		}
+		s.txShard = i
+	}
+	return sess, nil
+}
+
+func (s *shardSession) Ping(ctx context.Context) bool {
+	for i := range s.conn.shards {
+		sess, err := s.session(i)
+		if err != nil || !sess.Ping(ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *shardSession) Query(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	i, err := s.conn.shard(ctx, stmt, params...)
+	if err != nil {
+		return nil, err
+	}
+	if i >= 0 {
+		sess, err := s.get(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		return sess.Query(ctx, stmt, params...)
+	}
+	return s.fanOut(ctx, func(sess session.Session) (resultset.Result, error) {
+		return sess.Query(ctx, stmt, params...)
+	})
+}
+
+func (s *shardSession) Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	i, err := s.conn.shard(ctx, stmt, params...)
+	if err != nil {
+		return nil, err
+	}
+	if i >= 0 {
+		sess, err := s.get(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		return sess.Execute(ctx, stmt, params...)
+	}
+	if !isAllShards(ctx) {
+		return nil, errors.ShardKeyNotFound
+	}
+	return s.fanOut(ctx, func(sess session.Session) (resultset.Result, error) {
+		return sess.Execute(ctx, stmt, params...)
+	})
+}
+
+// fanOut runs f on all shards concurrently and merges the results in shard order.
+func (s *shardSession) fanOut(ctx context.Context, f func(sess session.Session) (resultset.Result, error)) (resultset.Result, error) {
+	if s.inTx {
+		return nil, fmt.Errorf("%w: statement without shard key in transaction ", errors.ShardCrossTransaction)
+	}
+	sessions := make([]session.Session, len(s.sessions))
+	for i := range sessions {
+		sess, err := s.get(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		sessions[i] = sess
+	}
+
+	results := make([]resultset.Result, len(sessions))
+	errs := make([]error, len(sessions))
+	wait := sync.WaitGroup{}
+	for i := range sessions {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			results[i], errs[i] = f(sessions[i])
+		}(i)
+	}
+	wait.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			for _, r := range results {
+				if r != nil {
+					_ = r.Close()
+				}
+			}
+			return nil, fmt.Errorf("shard %d: %w", i, err)
+		}
+	}
+	return resultset.NewMultiResult(results...), nil
+}
+
+// ExecuteBatch groups params by shard, items of result are in the order of params.
+// Shards are executed in turn and each shard commits its own items, a batch crossing shards is NOT atomic: if a shard
+// fails, the items of the shards before it remain committed, the items of the shards after it are not executed and
+// marked with errors.ShardBatchAborted. The result is returned with the error of the failed shard.
+func (s *shardSession) ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error) {
+	var order []int
+	groups := map[int][]int{}
+	for i, p := range params {
+		shard, err := s.conn.shard(ctx, stmt, p...)
+		if err != nil {
+			return nil, err
+		}
+		if shard < 0 {
+			return nil, errors.ShardKeyNotFound
+		}
+		if _, ok := groups[shard]; !ok {
+			order = append(order, shard)
+		}
+		groups[shard] = append(groups[shard], i)
+	}
+
+	items := make([]resultset.BatchItem, len(params))
+	for i := range items {
+		items[i] = resultset.BatchItem{RowsAffected: -1, Err: errors.ShardBatchAborted}
+	}
+	ret := &resultset.BatchResult{Items: items}
+	var partial error
+	for _, shard := range order {
+		indexes := groups[shard]
+		r, err := s.executeBatch(ctx, shard, stmt, params, indexes)
+		n := 0
+		if r != nil {
+			for j, item := range r.Items {
+				items[indexes[j]] = item
+			}
+			n = len(r.Items)
+		}
+		if err != nil {
+			if err != errors.BatchPartialError {
+				for _, i := range indexes[n:] {
+					items[i].Err = err
+				}
+				return ret, fmt.Errorf("shard %d: %w", shard, err)
+			}
+			partial = err
+		}
+	}
+	return ret, partial
+}
+
+func (s *shardSession) executeBatch(ctx context.Context, shard int, stmt string, params [][]interface{}, indexes []int) (*resultset.BatchResult, error) {
+	sess, err := s.get(ctx, shard)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]interface{}, len(indexes))
+	for j, i := range indexes {
+		rows[j] = params[i]
+	}
+	return sess.ExecuteBatch(ctx, stmt, rows)
+}
+
+// Begin binds the transaction to the shard of the key in context, otherwise to the shard of the first statement.
+func (s *shardSession) Begin(ctx context.Context) error {
+	if s.inTx {
+		return errors.TransactionHaveBegin
+	}
+	s.inTx = true
+	s.txShard = -1
+	if key, ok := GetShardKey(ctx); ok {
+		i, err := s.conn.route(key)
+		if err == nil {
+			_, err = s.get(ctx, i)
+		}
+		if err != nil {
+			s.inTx = false
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *shardSession) Commit(ctx context.Context) error {
+	return s.end(func(sess session.Session) error {
+		return sess.Commit(ctx)
+	})
+}
+
+func (s *shardSession) Rollback(ctx context.Context) error {
+	return s.end(func(sess session.Session) error {
+		return sess.Rollback(ctx)
+	})
+}
+
+func (s *shardSession) end(f func(sess session.Session) error) error {
+	if !s.inTx {
+		return errors.TransactionWithoutBegin
+	}
+	// the transaction is kept if f fails, e.g. Rollback is allowed after Commit failed
+	if s.txShard >= 0 {
+		if err := f(s.sessions[s.txShard]); err != nil {
+			return err
+		}
+	}
+	s.inTx = false
+	s.txShard = -1
+	return nil
+}
+
+func (s *shardSession) Close() error {
+	var ret error
+	for i, sess := range s.sessions {
+		if sess == nil {
+			continue
+		}
+		if err := sess.Close(); err != nil && ret == nil {
+			ret = err
+		}
+		s.sessions[i] = nil
+	}
+	return ret
+}
+
+type shardOpts struct{}
+
+var ShardOpts shardOpts
+
+func (shardOpts) SetStrategy(strategy ShardStrategy) ShardOpt {
+	return func(c *shardConnection) {
+		c.strategy = strategy
+	}
+}
+
+// SetKeyExtractor sets the extractor used when there is no shard key in context.
+func (shardOpts) SetKeyExtractor(extractor ShardKeyExtractor) ShardOpt {
+	return func(c *shardConnection) {
+		c.extractor = extractor
+	}
+}
@@ -0,0 +1,38 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package drivers is the registry of connection factories, driver packages register themselves in init:
+//
+//	func init() {
+//		drivers.Register("clickhouse", func(url string) (connection.Connection, error) {
+//			...
+//		})
+//	}
+//
+// Then connections can be created by connection.Open("clickhouse://...") after the driver package is imported.
+package drivers
+
+import "github.com/xfali/lean/connection"
+
+// Register makes a connection factory available by the scheme of url, it panics if the scheme is registered twice.
+func Register(scheme string, factory connection.Factory) {
+	connection.Register(scheme, factory)
+}
+
+// Drivers returns the sorted registered schemes.
+func Drivers() []string {
+	return connection.Drivers()
+}
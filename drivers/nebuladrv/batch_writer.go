@@ -0,0 +1,339 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nebuladrv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/xfali/lean/connection"
+	"github.com/xfali/lean/drivers/ngql"
+	"github.com/xfali/lean/session"
+	"github.com/xfali/xlog"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBatchSize   = 256
+	DefaultConcurrency = 4
+)
+
+// RetryPolicy returns whether a failed batch should be retried and the interval before the next attempt.
+// attempt starts from 1.
+type RetryPolicy func(attempt int, err error) (time.Duration, bool)
+
+func NoRetry(attempt int, err error) (time.Duration, bool) {
+	return 0, false
+}
+
+func FixedRetry(maxAttempts int, interval time.Duration) RetryPolicy {
+	return func(attempt int, err error) (time.Duration, bool) {
+		return interval, attempt < maxAttempts
+	}
+}
+
+func ExponentialRetry(maxAttempts int, initial, max time.Duration) RetryPolicy {
+	return func(attempt int, err error) (time.Duration, bool) {
+		if attempt >= maxAttempts {
+			return 0, false
+		}
+		d := initial << (attempt - 1)
+		if d <= 0 || d > max {
+			d = max
+		}
+		return d, true
+	}
+}
+
+type BatchOpt func(*BatchWriter)
+
+// BatchError describes a failed batch, Offset is the index of the first item of the batch in the input.
+type BatchError struct {
+	Batch    int
+	Offset   int
+	Size     int
+	Attempts int
+	Err      error
+}
+
+func (e BatchError) Error() string {
+	return fmt.Sprintf("Batch %d (offset %d size %d) failed after %d attempts: %v ", e.Batch, e.Offset, e.Size, e.Attempts, e.Err)
+}
+
+type BatchResult struct {
+	Total     int
+	Succeeded int
+	Batches   int
+	Failures  []BatchError
+}
+
+type BatchWriter struct {
+	logger      xlog.Logger
+	conn        connection.Connection
+	space       string
+	batchSize   int
+	concurrency int
+	ifNotExists bool
+	retry       RetryPolicy
+	onFailure   func(BatchError)
+}
+
+type batch struct {
+	index  int
+	offset int
+	values []interface{}
+}
+
+// NewBatchWriter creates a writer which inserts tagged structs (see ngql package) with sessions got from conn.
+func NewBatchWriter(conn connection.Connection, opts ...BatchOpt) *BatchWriter {
+	ret := &BatchWriter{
+		logger:      xlog.GetLogger(),
+		conn:        conn,
+		batchSize:   DefaultBatchSize,
+		concurrency: DefaultConcurrency,
+		retry:       NoRetry,
+	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
+}
+
+// WriteVertices inserts values into tag, values must be a slice, array or channel of tagged structs.
+func (w *BatchWriter) WriteVertices(ctx context.Context, tag string, values interface{}) (*BatchResult, error) {
+	return w.write(ctx, values, func(vs []interface{}) ngql.Builder {
+		b := ngql.InsertVertex(tag, vs...)
+		if w.ifNotExists {
+			b.IfNotExists()
+		}
+		return b
+	})
+}
+
+// WriteEdges inserts values into edge, values must be a slice, array or channel of tagged structs.
+func (w *BatchWriter) WriteEdges(ctx context.Context, edge string, values interface{}) (*BatchResult, error) {
+	return w.write(ctx, values, func(vs []interface{}) ngql.Builder {
+		b := ngql.InsertEdge(edge, vs...)
+		if w.ifNotExists {
+			b.IfNotExists()
+		}
+		return b
+	})
+}
+
+func (w *BatchWriter) write(ctx context.Context, values interface{}, builder func([]interface{}) ngql.Builder) (*BatchResult, error) {
+	rv := reflect.ValueOf(values)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Chan:
+	default:
+		return nil, fmt.Errorf("Batch values expect slice or channel but get %T ", values)
+	}
+	if w.batchSize <= 0 || w.concurrency <= 0 {
+		return nil, errors.New("Batch size and concurrency must be positive ")
+	}
+
+	sessions := make([]session.Session, 0, w.concurrency)
+	defer func() {
+		for _, s := range sessions {
+			_ = s.Close()
+		}
+	}()
+	for i := 0; i < w.concurrency; i++ {
+		sess, err := w.conn.GetSession()
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+		if w.space != "" {
+			id, err := ngql.Identifier(w.space)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := sess.Execute(ctx, "USE "+id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := &BatchResult{}
+	var locker sync.Mutex
+	batches := make(chan batch, w.concurrency)
+	wg := sync.WaitGroup{}
+	for _, sess := range sessions {
+		wg.Add(1)
+		go func(sess session.Session) {
+			defer wg.Done()
+			for b := range batches {
+				attempts, err := w.execute(ctx, sess, builder(b.values))
+				locker.Lock()
+				if err != nil {
+					be := BatchError{
+						Batch:    b.index,
+						Offset:   b.offset,
+						Size:     len(b.values),
+						Attempts: attempts,
+						Err:      err,
+					}
+					result.Failures = append(result.Failures, be)
+					if w.onFailure != nil {
+						w.onFailure(be)
+					}
+				} else {
+					result.Succeeded += len(b.values)
+				}
+				locker.Unlock()
+			}
+		}(sess)
+	}
+
+	err := w.dispatch(ctx, rv, batches, result)
+	close(batches)
+	wg.Wait()
+	if err != nil {
+		return result, err
+	}
+	if len(result.Failures) > 0 {
+		return result, fmt.Errorf("Nebula batch write failed: %d of %d batches ", len(result.Failures), result.Batches)
+	}
+	return result, nil
+}
+
+func (w *BatchWriter) dispatch(ctx context.Context, rv reflect.Value, batches chan<- batch, result *BatchResult) error {
+	cur := batch{}
+	send := func() error {
+		if len(cur.values) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case batches <- cur:
+		}
+		result.Batches++
+		cur = batch{
+			index:  cur.index + 1,
+			offset: result.Total,
+		}
+		return nil
+	}
+	add := func(v reflect.Value) error {
+		cur.values = append(cur.values, v.Interface())
+		result.Total++
+		if len(cur.values) >= w.batchSize {
+			return send()
+		}
+		return nil
+	}
+
+	if rv.Kind() == reflect.Chan {
+		done := reflect.ValueOf(ctx.Done())
+		for {
+			chosen, v, ok := reflect.Select([]reflect.SelectCase{
+				{Dir: reflect.SelectRecv, Chan: done},
+				{Dir: reflect.SelectRecv, Chan: rv},
+			})
+			if chosen == 0 {
+				return ctx.Err()
+			}
+			if !ok {
+				break
+			}
+			if err := add(v); err != nil {
+				return err
+			}
+		}
+	} else {
+		for i := 0; i < rv.Len(); i++ {
+			if err := add(rv.Index(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return send()
+}
+
+func (w *BatchWriter) execute(ctx context.Context, sess session.Session, b ngql.Builder) (int, error) {
+	stmt, params, err := b.Build()
+	if err != nil {
+		return 0, err
+	}
+	attempt := 0
+	for {
+		attempt++
+		_, err = sess.Execute(ctx, stmt, params...)
+		if err == nil {
+			return attempt, nil
+		}
+		interval, retry := w.retry(attempt, err)
+		if !retry {
+			return attempt, err
+		}
+		w.logger.Warnf("Nebula batch write failed, retry %d after %s: %v\n", attempt, interval, err)
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+type batchOpts struct{}
+
+var BatchOpts batchOpts
+
+// SetSpace executes USE space on every session before writing.
+func (batchOpts) SetSpace(space string) BatchOpt {
+	return func(writer *BatchWriter) {
+		writer.space = space
+	}
+}
+
+func (batchOpts) SetBatchSize(size int) BatchOpt {
+	return func(writer *BatchWriter) {
+		writer.batchSize = size
+	}
+}
+
+func (batchOpts) SetConcurrency(concurrency int) BatchOpt {
+	return func(writer *BatchWriter) {
+		writer.concurrency = concurrency
+	}
+}
+
+func (batchOpts) SetIfNotExists(ifNotExists bool) BatchOpt {
+	return func(writer *BatchWriter) {
+		writer.ifNotExists = ifNotExists
+	}
+}
+
+func (batchOpts) SetRetryPolicy(retry RetryPolicy) BatchOpt {
+	return func(writer *BatchWriter) {
+		writer.retry = retry
+	}
+}
+
+// SetFailureHandler sets a callback invoked for every failed batch, it is called serially.
+func (batchOpts) SetFailureHandler(handler func(BatchError)) BatchOpt {
+	return func(writer *BatchWriter) {
+		writer.onFailure = handler
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nebuladrv
+
+import (
+	"context"
+	"errors"
+	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/session"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testVertex struct {
+	Id   int64  `nebula:"vid"`
+	Name string `column:"name"`
+}
+
+type testConnection struct {
+	locker   sync.Mutex
+	stmts    []string
+	failures int32
+}
+
+func (c *testConnection) Open() error {
+	return nil
+}
+
+func (c *testConnection) GetSession() (session.Session, error) {
+	return &testSession{conn: c}, nil
+}
+
+func (c *testConnection) Close() error {
+	return nil
+}
+
+type testSession struct {
+	session.Session
+	conn *testConnection
+}
+
+func (s *testSession) Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	if strings.Contains(stmt, `"fail"`) && atomic.AddInt32(&s.conn.failures, -1) >= 0 {
+		return nil, errors.New("test failure")
+	}
+	s.conn.locker.Lock()
+	defer s.conn.locker.Unlock()
+	s.conn.stmts = append(s.conn.stmts, stmt)
+	return nil, nil
+}
+
+func (s *testSession) Close() error {
+	return nil
+}
+
+func TestBatchWriter(t *testing.T) {
+	values := make([]testVertex, 10)
+	for i := range values {
+		values[i] = testVertex{Id: int64(i), Name: "v"}
+	}
+	t.Run("slice", func(t *testing.T) {
+		conn := &testConnection{}
+		w := NewBatchWriter(conn, BatchOpts.SetBatchSize(3), BatchOpts.SetConcurrency(2), BatchOpts.SetSpace("test"))
+		ret, err := w.WriteVertices(context.Background(), "player", values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret.Total != 10 || ret.Succeeded != 10 || ret.Batches != 4 {
+			t.Fatal(ret)
+		}
+		// 2 USE + 4 INSERT
+		if len(conn.stmts) != 6 {
+			t.Fatal(conn.stmts)
+		}
+	})
+	t.Run("channel", func(t *testing.T) {
+		conn := &testConnection{}
+		ch := make(chan testVertex)
+		go func() {
+			for _, v := range values {
+				ch <- v
+			}
+			close(ch)
+		}()
+		ret, err := NewBatchWriter(conn, BatchOpts.SetBatchSize(4)).WriteVertices(context.Background(), "player", ch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret.Total != 10 || ret.Succeeded != 10 || ret.Batches != 3 {
+			t.Fatal(ret)
+		}
+	})
+	t.Run("retry", func(t *testing.T) {
+		vs := append([]testVertex{{Id: 100, Name: "fail"}}, values...)
+		conn := &testConnection{failures: 1}
+		ret, err := NewBatchWriter(conn, BatchOpts.SetBatchSize(5),
+			BatchOpts.SetRetryPolicy(FixedRetry(2, time.Millisecond))).WriteVertices(context.Background(), "player", vs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret.Succeeded != 11 {
+			t.Fatal(ret)
+		}
+
+		conn = &testConnection{failures: 10}
+		ret, err = NewBatchWriter(conn, BatchOpts.SetBatchSize(5),
+			BatchOpts.SetRetryPolicy(FixedRetry(2, time.Millisecond))).WriteVertices(context.Background(), "player", vs)
+		if err == nil {
+			t.Fatal("expect error")
+		}
+		if ret.Succeeded != 6 || len(ret.Failures) != 1 || ret.Failures[0].Offset != 0 || ret.Failures[0].Attempts != 2 {
+			t.Fatal(ret)
+		}
+	})
+}
@@ -17,14 +17,37 @@
 package nebuladrv
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	nebula "github.com/vesoft-inc/nebula-go/v3"
+	"github.com/xfali/lean/connection"
+	"github.com/xfali/lean/drivers/ngql"
+	lerrors "github.com/xfali/lean/errors"
 	"github.com/xfali/lean/session"
 	"github.com/xfali/xlog"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+const (
+	DefaultReconnectMinBackoff = 500 * time.Millisecond
+	DefaultReconnectMaxBackoff = 30 * time.Second
+	defaultPingTimeout         = time.Second
+)
+
+type poolCreator func(addresses []nebula.HostAddress, conf nebula.PoolConfig, sslConfig *tls.Config) (*nebula.ConnectionPool, error)
+
+func defaultPoolCreator(addresses []nebula.HostAddress, conf nebula.PoolConfig, sslConfig *tls.Config) (*nebula.ConnectionPool, error) {
+	return nebula.NewSslConnectionPool(addresses, conf, sslConfig, &logger{
+		log: xlog.GetLogger(),
+	})
+}
+
 type nebulaConnection struct {
 	pool      *nebula.ConnectionPool
 	addresses []nebula.HostAddress
@@ -32,18 +55,45 @@ type nebulaConnection struct {
 	sslConfig *tls.Config
 	username  string
 	password  string
+	space     string
+
+	sessOpts []SessionOpt
+	timeout  time.Duration
+
+	lazy       bool
+	reconnect  bool
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	creator    poolCreator
+
+	locker    sync.Mutex
+	opened    bool
+	backoff   time.Duration
+	nextRetry time.Time
+	lastErr   error
+	stopC     chan struct{}
+	retrying  bool
+	// connecting is set while the pool is being created outside the lock
+	connecting bool
+	inUse      int64
 }
 
 type ConnectionOpt func(*nebulaConnection)
 
 func NewNebulaConnection(opts ...ConnectionOpt) *nebulaConnection {
-	ret := &nebulaConnection{}
+	ret := &nebulaConnection{
+		minBackoff: DefaultReconnectMinBackoff,
+		maxBackoff: DefaultReconnectMaxBackoff,
+		creator:    defaultPoolCreator,
+	}
 	for _, opt := range opts {
 		opt(ret)
 	}
 	return ret
 }
 
+// Open creates the pool, if lazy is set the pool is created by the first GetSession.
+// If reconnect is set and graphd is unavailable, Open succeeds and the pool is created in background.
 func (c *nebulaConnection) Open() error {
 	if c.username == "" {
 		return errors.New("Nebula username is empty ")
@@ -53,33 +103,304 @@ func (c *nebulaConnection) Open() error {
 		return errors.New("Nebula password is empty ")
 	}
 
-	if c.pool == nil {
-		p, err := nebula.NewSslConnectionPool(c.addresses, c.conf, c.sslConfig, &logger{
-			log: xlog.GetLogger(),
-		})
-		if err != nil {
-			return fmt.Errorf("Nebula connect init failed: %v ", err)
-		}
-		c.pool = p
+	c.locker.Lock()
+	if c.opened {
+		c.locker.Unlock()
+		return nil
+	}
+	c.opened = true
+	c.stopC = make(chan struct{})
+	if c.pool != nil || c.lazy {
+		c.locker.Unlock()
+		return nil
 	}
+	c.locker.Unlock()
 
+	if _, err := c.connect(); err != nil && err != errConnecting {
+		c.locker.Lock()
+		defer c.locker.Unlock()
+		if !c.reconnect {
+			c.opened = false
+			return err
+		}
+		c.startRetry()
+	}
 	return nil
 }
 
-func (c *nebulaConnection) GetSession() (session.Session, error) {
-	if c.pool == nil {
+var errConnecting = errors.New("Nebula is connecting ")
+
+// connect creates the pool without holding the lock, so that dialing does not block the other methods.
+// It returns errConnecting if the pool is being created by another goroutine.
+func (c *nebulaConnection) connect() (*nebula.ConnectionPool, error) {
+	c.locker.Lock()
+	if c.pool != nil {
+		defer c.locker.Unlock()
+		return c.pool, nil
+	}
+	if c.connecting {
+		c.locker.Unlock()
+		return nil, errConnecting
+	}
+	c.connecting = true
+	c.locker.Unlock()
+
+	p, err := c.creator(c.addresses, c.conf, c.sslConfig)
+
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.connecting = false
+	if err != nil {
+		c.lastErr = fmt.Errorf("Nebula connect init failed: %v ", err)
+		if c.backoff == 0 {
+			c.backoff = c.minBackoff
+		} else if c.backoff *= 2; c.backoff > c.maxBackoff {
+			c.backoff = c.maxBackoff
+		}
+		c.nextRetry = time.Now().Add(c.backoff)
+		return nil, c.lastErr
+	}
+	// closed while connecting
+	if !c.opened {
+		p.Close()
+		return nil, errors.New("Connection has been closed ")
+	}
+	c.pool = p
+	c.lastErr = nil
+	c.backoff = 0
+	c.nextRetry = time.Time{}
+	return p, nil
+}
+
+// startRetry reconnects in background until the pool is created or connection is closed,
+// the caller must hold the lock. Close does not wait for the goroutine, it exits when it wakes up.
+func (c *nebulaConnection) startRetry() {
+	if c.retrying || !c.opened {
+		return
+	}
+	c.retrying = true
+	go func(stopC <-chan struct{}) {
+		var pause time.Duration
+		for {
+			c.locker.Lock()
+			wait := time.Until(c.nextRetry)
+			c.locker.Unlock()
+			if wait < pause {
+				wait = pause
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-stopC:
+				timer.Stop()
+				c.locker.Lock()
+				c.retrying = false
+				c.locker.Unlock()
+				return
+			case <-timer.C:
+			}
+
+			c.locker.Lock()
+			done := !c.opened || c.pool != nil
+			c.locker.Unlock()
+			pause = 0
+			if !done {
+				_, err := c.connect()
+				done = err == nil
+				// wait for the other goroutine connecting
+				if err == errConnecting {
+					pause = c.minBackoff
+				}
+			}
+			if done {
+				c.locker.Lock()
+				c.retrying = false
+				c.locker.Unlock()
+				return
+			}
+		}
+	}(c.stopC)
+}
+
+// getPool returns the pool, creates it if it is not created and the backoff is elapsed.
+func (c *nebulaConnection) getPool() (*nebula.ConnectionPool, error) {
+	c.locker.Lock()
+	if c.pool != nil {
+		defer c.locker.Unlock()
+		return c.pool, nil
+	}
+	if !c.opened {
+		c.locker.Unlock()
 		return nil, errors.New("Connection must be open before get session. ")
 	}
-	sess, err := c.pool.GetSession(c.username, c.password)
+	try := !c.retrying && !c.connecting && !time.Now().Before(c.nextRetry)
+	c.locker.Unlock()
+
+	if try {
+		if p, err := c.connect(); err == nil {
+			return p, nil
+		}
+	}
+
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	if c.pool != nil {
+		return c.pool, nil
+	}
+	if c.reconnect && !c.connecting {
+		c.startRetry()
+	}
+	if c.lastErr == nil {
+		return nil, fmt.Errorf("%w: %v ", lerrors.BackendUnavailable, errConnecting)
+	}
+	return nil, fmt.Errorf("%w: %v ", lerrors.BackendUnavailable, c.lastErr)
+}
+
+// broken closes the pool if all hosts are unreachable, the pool will be recreated.
+func (c *nebulaConnection) broken(pool *nebula.ConnectionPool, cause error) bool {
+	if c.pingHosts(pool, c.pingTimeout(context.Background())) {
+		return false
+	}
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	if c.pool == pool {
+		pool.Close()
+		c.pool = nil
+		c.lastErr = cause
+		c.backoff = 0
+		c.nextRetry = time.Now().Add(c.minBackoff)
+		if c.reconnect {
+			c.startRetry()
+		}
+	}
+	return true
+}
+
+func (c *nebulaConnection) GetSession() (session.Session, error) {
+	pool, err := c.getPool()
 	if err != nil {
+		return nil, err
+	}
+	sess, err := pool.GetSession(c.username, c.password)
+	if err != nil {
+		if c.broken(pool, err) {
+			return nil, fmt.Errorf("%w: %v ", lerrors.BackendUnavailable, err)
+		}
 		return nil, fmt.Errorf("Get nebula session failed: %v ", err)
 	}
-	return NewNebulaSession(sess), nil
+	if c.space != "" {
+		id, err := ngql.Identifier(c.space)
+		if err == nil {
+			err = CheckResultSet(sess.Execute("USE " + id))
+		}
+		if err != nil {
+			sess.Release()
+			return nil, fmt.Errorf("Use space %s failed: %v ", c.space, err)
+		}
+	}
+	opts := c.sessOpts
+	if c.timeout > 0 {
+		opts = append([]SessionOpt{SessOpts.SetStatementTimeout(c.timeout)}, opts...)
+	}
+	ret := NewNebulaSession(sess, opts...)
+	atomic.AddInt64(&c.inUse, 1)
+	ret.onClose = func() {
+		atomic.AddInt64(&c.inUse, -1)
+	}
+	return ret, nil
+}
+
+// PoolStats returns whether the pool is up, max connections and the sessions in use, each holds one connection.
+func (c *nebulaConnection) PoolStats() connection.PoolStats {
+	c.locker.Lock()
+	up := c.pool != nil
+	c.locker.Unlock()
+	maxOpen := c.conf.MaxConnPoolSize
+	if maxOpen == 0 {
+		maxOpen = nebula.GetDefaultConf().MaxConnPoolSize
+	}
+	return connection.PoolStats{
+		Up:      up,
+		MaxOpen: maxOpen,
+		InUse:   int(atomic.LoadInt64(&c.inUse)),
+	}
+}
+
+// Ping returns true if any host is healthy.
+func (c *nebulaConnection) Ping(ctx context.Context) bool {
+	c.locker.Lock()
+	pool := c.pool
+	c.locker.Unlock()
+	return c.pingHosts(pool, c.pingTimeout(ctx))
+}
+
+// HostStatus returns the health of hosts by host:port.
+func (c *nebulaConnection) HostStatus(ctx context.Context) map[string]bool {
+	c.locker.Lock()
+	pool := c.pool
+	c.locker.Unlock()
+	timeout := c.pingTimeout(ctx)
+	ret := make(map[string]bool, len(c.addresses))
+	for _, addr := range c.addresses {
+		ret[hostString(addr)] = c.pingHost(pool, addr, timeout) == nil
+	}
+	return ret
+}
+
+func (c *nebulaConnection) pingTimeout(ctx context.Context) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		return time.Until(deadline)
+	}
+	if c.conf.TimeOut > 0 {
+		return c.conf.TimeOut
+	}
+	return defaultPingTimeout
+}
+
+func (c *nebulaConnection) pingHosts(pool *nebula.ConnectionPool, timeout time.Duration) bool {
+	for _, addr := range c.addresses {
+		if c.pingHost(pool, addr, timeout) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// pingHost checks host by pool, or dials it if the pool is not created.
+func (c *nebulaConnection) pingHost(pool *nebula.ConnectionPool, addr nebula.HostAddress, timeout time.Duration) error {
+	if pool != nil {
+		return pool.Ping(addr, timeout)
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	if c.sslConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostString(addr), c.sslConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", hostString(addr))
+	}
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func hostString(addr nebula.HostAddress) string {
+	return net.JoinHostPort(addr.Host, strconv.Itoa(addr.Port))
 }
 
 func (c *nebulaConnection) Close() error {
+	c.locker.Lock()
+	if c.stopC != nil {
+		close(c.stopC)
+		c.stopC = nil
+	}
+	c.opened = false
+	defer c.locker.Unlock()
+	// the pool being created is closed by connect
 	if c.pool != nil {
 		c.pool.Close()
+		c.pool = nil
 	}
 	return nil
 }
@@ -88,6 +409,33 @@ type connOpts struct{}
 
 var ConnOpts connOpts
 
+func (connOpts) SetCreateSessionOpts(opts ...SessionOpt) ConnectionOpt {
+	return func(connection *nebulaConnection) {
+		connection.sessOpts = opts
+	}
+}
+
+// SetLazy creates the pool when the first session is got instead of Open.
+func (connOpts) SetLazy(lazy bool) ConnectionOpt {
+	return func(connection *nebulaConnection) {
+		connection.lazy = lazy
+	}
+}
+
+// SetReconnect recreates the pool in background with exponential backoff from minBackoff to maxBackoff
+// when graphd is unavailable, GetSession returns errors.BackendUnavailable until the pool is recreated.
+func (connOpts) SetReconnect(minBackoff, maxBackoff time.Duration) ConnectionOpt {
+	return func(connection *nebulaConnection) {
+		connection.reconnect = true
+		if minBackoff > 0 {
+			connection.minBackoff = minBackoff
+		}
+		if maxBackoff >= connection.minBackoff {
+			connection.maxBackoff = maxBackoff
+		}
+	}
+}
+
 func (connOpts) WithConnectionPool(pool *nebula.ConnectionPool) ConnectionOpt {
 	return func(connection *nebulaConnection) {
 		connection.pool = pool
@@ -101,6 +449,20 @@ func (connOpts) WithUserInfo(username, password string) ConnectionOpt {
 	}
 }
 
+// SetStatementTimeout sets the default statement timeout of sessions, see SessOpts.SetStatementTimeout.
+func (connOpts) SetStatementTimeout(timeout time.Duration) ConnectionOpt {
+	return func(connection *nebulaConnection) {
+		connection.timeout = timeout
+	}
+}
+
+// SetSpace executes USE space when session is created.
+func (connOpts) SetSpace(space string) ConnectionOpt {
+	return func(connection *nebulaConnection) {
+		connection.space = space
+	}
+}
+
 func (connOpts) SetAddresses(addresses []nebula.HostAddress) ConnectionOpt {
 	return func(connection *nebulaConnection) {
 		connection.addresses = addresses
@@ -0,0 +1,144 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nebuladrv
+
+import (
+	"context"
+	"crypto/tls"
+	stderrors "errors"
+	nebula "github.com/vesoft-inc/nebula-go/v3"
+	"github.com/xfali/lean/errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConnectionLazy(t *testing.T) {
+	var attempts int32
+	conn := NewNebulaConnection(
+		ConnOpts.WithUserInfo("root", "nebula"),
+		ConnOpts.AddAddress("127.0.0.1", 1),
+		ConnOpts.SetLazy(true),
+		ConnOpts.SetReconnect(time.Hour, time.Hour))
+	conn.creator = func(addresses []nebula.HostAddress, conf nebula.PoolConfig, sslConfig *tls.Config) (*nebula.ConnectionPool, error) {
+		atomic.AddInt32(&attempts, 1)
+		return nil, stderrors.New("graphd down")
+	}
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if atomic.LoadInt32(&attempts) != 0 {
+		t.Fatal("expect no connecting when open lazily")
+	}
+
+	_, err := conn.GetSession()
+	if !stderrors.Is(err, errors.BackendUnavailable) {
+		t.Fatal("expect BackendUnavailable but get ", err)
+	}
+	// backoff is not elapsed
+	_, err = conn.GetSession()
+	if !stderrors.Is(err, errors.BackendUnavailable) || atomic.LoadInt32(&attempts) != 1 {
+		t.Fatal("expect connecting once but get ", attempts, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if conn.Ping(ctx) {
+		t.Fatal("expect host unhealthy")
+	}
+	if status := conn.HostStatus(ctx); status["127.0.0.1:1"] {
+		t.Fatal("unexpected host status ", status)
+	}
+}
+
+func TestConnectionReconnect(t *testing.T) {
+	var attempts int32
+	conn := NewNebulaConnection(
+		ConnOpts.WithUserInfo("root", "nebula"),
+		ConnOpts.AddAddress("127.0.0.1", 1),
+		ConnOpts.SetReconnect(5*time.Millisecond, 20*time.Millisecond))
+	conn.creator = func(addresses []nebula.HostAddress, conf nebula.PoolConfig, sslConfig *tls.Config) (*nebula.ConnectionPool, error) {
+		atomic.AddInt32(&attempts, 1)
+		return nil, stderrors.New("graphd down")
+	}
+	if err := conn.Open(); err != nil {
+		t.Fatal("expect open succeeded with reconnect but get ", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	n := atomic.LoadInt32(&attempts)
+	if n < 3 {
+		t.Fatal("expect reconnecting in background but get attempts ", n)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if atomic.LoadInt32(&attempts) != n {
+		t.Fatal("expect reconnecting stopped after close")
+	}
+	if _, err := conn.GetSession(); err == nil || stderrors.Is(err, errors.BackendUnavailable) {
+		t.Fatal("expect not opened error but get ", err)
+	}
+
+	conn = NewNebulaConnection(
+		ConnOpts.WithUserInfo("root", "nebula"),
+		ConnOpts.AddAddress("127.0.0.1", 1))
+	conn.creator = func(addresses []nebula.HostAddress, conf nebula.PoolConfig, sslConfig *tls.Config) (*nebula.ConnectionPool, error) {
+		return nil, stderrors.New("graphd down")
+	}
+	if err := conn.Open(); err == nil {
+		t.Fatal("expect open failed without reconnect")
+	}
+}
+
+func TestConnectionConnectWithoutLock(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	conn := NewNebulaConnection(
+		ConnOpts.WithUserInfo("root", "nebula"),
+		ConnOpts.AddAddress("127.0.0.1", 1))
+	conn.creator = func(addresses []nebula.HostAddress, conf nebula.PoolConfig, sslConfig *tls.Config) (*nebula.ConnectionPool, error) {
+		close(entered)
+		<-release
+		return nil, stderrors.New("graphd down")
+	}
+	opened := make(chan error, 1)
+	go func() {
+		opened <- conn.Open()
+	}()
+	<-entered
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.PoolStats()
+		if _, err := conn.GetSession(); !stderrors.Is(err, errors.BackendUnavailable) {
+			t.Error("expect BackendUnavailable while connecting but get ", err)
+		}
+		conn.Close()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expect not blocked by connecting")
+	}
+	close(release)
+	if err := <-opened; err == nil {
+		t.Fatal("expect open failed")
+	}
+}
@@ -0,0 +1,243 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nebuladrv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	nebula "github.com/vesoft-inc/nebula-go/v3"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/graph"
+	"strconv"
+)
+
+// JsonResultSetEx is implemented by the result of statements executed in json mode.
+type JsonResultSetEx interface {
+	ResultSetEx
+
+	// GetJson returns the raw json response.
+	GetJson() []byte
+}
+
+type jsonResponse struct {
+	Results []jsonResult `json:"results"`
+	Errors  []jsonError  `json:"errors"`
+}
+
+type jsonError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type jsonResult struct {
+	Columns     []string      `json:"columns"`
+	Data        []jsonRow     `json:"data"`
+	LatencyInUs int64         `json:"latencyInUs"`
+	SpaceName   string        `json:"spaceName"`
+	PlanDesc    *jsonPlanDesc `json:"planDesc"`
+	Comment     string        `json:"comment"`
+}
+
+type jsonRow struct {
+	Row  []interface{} `json:"row"`
+	Meta []interface{} `json:"meta"`
+}
+
+type jsonPlanDesc struct {
+	PlanNodeDescs    []jsonPlanNode   `json:"planNodeDescs"`
+	NodeIndexMap     map[string]int64 `json:"nodeIndexMap"`
+	Format           string           `json:"format"`
+	OptimizeTimeInUs int32            `json:"optimize_time_in_us"`
+}
+
+type jsonPlanNode struct {
+	Name        string            `json:"name"`
+	Id          int64             `json:"id"`
+	OutputVar   string            `json:"outputVar"`
+	Description map[string]string `json:"description"`
+	Profiles    []struct {
+		Rows              int64             `json:"rows"`
+		ExecDurationInUs  int64             `json:"execDurationInUs"`
+		TotalDurationInUs int64             `json:"totalDurationInUs"`
+		OtherStats        map[string]string `json:"otherStats"`
+	} `json:"profiles"`
+	BranchInfo *struct {
+		IsDoBranch      bool  `json:"isDoBranch"`
+		ConditionNodeId int64 `json:"conditionNodeId"`
+	} `json:"branchInfo"`
+	Dependencies []int64 `json:"dependencies"`
+}
+
+type nebulaJsonResultSet struct {
+	data   []byte
+	result jsonResult
+	index  int
+}
+
+func NewNebulaJsonResultSet(data []byte) (*nebulaJsonResultSet, error) {
+	resp := jsonResponse{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&resp); err != nil {
+		return nil, fmt.Errorf("Nebula json result decode failed: %v ", err)
+	}
+	for _, e := range resp.Errors {
+		if e.Code != int(nebula.ErrorCode_SUCCEEDED) {
+			return nil, fmt.Errorf("Nebula execute failed, code: %d message: %s ", e.Code, e.Message)
+		}
+	}
+	ret := &nebulaJsonResultSet{
+		data: data,
+	}
+	if len(resp.Results) > 0 {
+		ret.result = resp.Results[0]
+	}
+	return ret, nil
+}
+
+func (r *nebulaJsonResultSet) Columns() ([]string, error) {
+	return r.result.Columns, nil
+}
+
+func (r *nebulaJsonResultSet) Next() bool {
+	return r.index < len(r.result.Data)
+}
+
+func (r *nebulaJsonResultSet) Scan(dest ...interface{}) error {
+	if r.index >= len(r.result.Data) {
+		return errors.New("No more rows ")
+	}
+	row := r.result.Data[r.index].Row
+	if len(row) != len(dest) {
+		return fmt.Errorf("Length are inconsistent: row %d dest %d ", len(row), len(dest))
+	}
+	for i := range row {
+		dst, ok := dest[i].(*interface{})
+		if !ok {
+			return fmt.Errorf("Only support Dest type *interface{} but get [%T] ", dest[i])
+		}
+		*dst = jsonValue(row[i])
+	}
+	r.index++
+	return nil
+}
+
+func (r *nebulaJsonResultSet) Close() error {
+	return nil
+}
+
+func (r *nebulaJsonResultSet) LastInsertId() (int64, error) {
+	return 0, errors.New("Not support ")
+}
+
+func (r *nebulaJsonResultSet) RowsAffected() (int64, error) {
+	return 0, errors.New("Not support ")
+}
+
+func (r *nebulaJsonResultSet) GetLatency() int64 {
+	return r.result.LatencyInUs
+}
+
+func (r *nebulaJsonResultSet) GetPlanDesc() *graph.PlanDescription {
+	pd := r.result.PlanDesc
+	if pd == nil {
+		return nil
+	}
+	ret := &graph.PlanDescription{
+		PlanNodeDescs:    make([]*graph.PlanNodeDescription, len(pd.PlanNodeDescs)),
+		NodeIndexMap:     make(map[int64]int64, len(pd.NodeIndexMap)),
+		Format:           []byte(pd.Format),
+		OptimizeTimeInUs: pd.OptimizeTimeInUs,
+	}
+	for k, v := range pd.NodeIndexMap {
+		if i, err := strconv.ParseInt(k, 10, 64); err == nil {
+			ret.NodeIndexMap[i] = v
+		}
+	}
+	for i, n := range pd.PlanNodeDescs {
+		node := &graph.PlanNodeDescription{
+			Name:         []byte(n.Name),
+			Id:           n.Id,
+			OutputVar:    []byte(n.OutputVar),
+			Dependencies: n.Dependencies,
+		}
+		for k, v := range n.Description {
+			node.Description = append(node.Description, &graph.Pair{Key: []byte(k), Value: []byte(v)})
+		}
+		for _, p := range n.Profiles {
+			stats := &graph.ProfilingStats{
+				Rows:              p.Rows,
+				ExecDurationInUs:  p.ExecDurationInUs,
+				TotalDurationInUs: p.TotalDurationInUs,
+			}
+			if len(p.OtherStats) > 0 {
+				stats.OtherStats = make(map[string][]byte, len(p.OtherStats))
+				for k, v := range p.OtherStats {
+					stats.OtherStats[k] = []byte(v)
+				}
+			}
+			node.Profiles = append(node.Profiles, stats)
+		}
+		if n.BranchInfo != nil {
+			node.BranchInfo = &graph.PlanNodeBranchInfo{
+				IsDoBranch:      n.BranchInfo.IsDoBranch,
+				ConditionNodeID: n.BranchInfo.ConditionNodeId,
+			}
+		}
+		ret.PlanNodeDescs[i] = node
+	}
+	return ret
+}
+
+func (r *nebulaJsonResultSet) GetSpaceName() string {
+	return r.result.SpaceName
+}
+
+func (r *nebulaJsonResultSet) GetComment() string {
+	return r.result.Comment
+}
+
+func (r *nebulaJsonResultSet) GetResultSet() *nebula.ResultSet {
+	return nil
+}
+
+func (r *nebulaJsonResultSet) GetJson() []byte {
+	return r.data
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch o := v.(type) {
+	case json.Number:
+		if i, err := o.Int64(); err == nil {
+			return i
+		}
+		f, _ := o.Float64()
+		return f
+	case []interface{}:
+		for i := range o {
+			o[i] = jsonValue(o[i])
+		}
+		return o
+	case map[string]interface{}:
+		for k := range o {
+			o[k] = jsonValue(o[k])
+		}
+		return o
+	}
+	return v
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nebuladrv
+
+import (
+	"github.com/xfali/lean/mapping"
+	"testing"
+)
+
+const testJsonResult = `{
+  "results": [{
+    "columns": ["name", "age"],
+    "data": [{"row": ["Tim", 42], "meta": [null, null]}, {"row": ["Tony", 36.5], "meta": [null, null]}],
+    "latencyInUs": 1024,
+    "spaceName": "basketball",
+    "planDesc": {
+      "planNodeDescs": [{"name": "Project", "id": 1, "outputVar": "__Project_1", "description": {"columns": "name"},
+        "profiles": [{"rows": 2, "execDurationInUs": 10, "totalDurationInUs": 20, "otherStats": {}}],
+        "dependencies": [0]}],
+      "nodeIndexMap": {"1": 0},
+      "format": "row",
+      "optimize_time_in_us": 5
+    },
+    "comment": "hello"
+  }],
+  "errors": [{"code": 0}]
+}`
+
+func TestJsonResultSet(t *testing.T) {
+	rs, err := NewNebulaJsonResultSet([]byte(testJsonResult))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ex JsonResultSetEx = rs
+	if ex.GetLatency() != 1024 || ex.GetSpaceName() != "basketball" || ex.GetComment() != "hello" {
+		t.Fatal(ex.GetLatency(), ex.GetSpaceName(), ex.GetComment())
+	}
+	pd := ex.GetPlanDesc()
+	if len(pd.PlanNodeDescs) != 1 || string(pd.PlanNodeDescs[0].Name) != "Project" || pd.NodeIndexMap[1] != 0 ||
+		pd.PlanNodeDescs[0].Profiles[0].Rows != 2 {
+		t.Fatal(pd)
+	}
+
+	var v []struct {
+		Name string  `column:"name"`
+		Age  float64 `column:"age"`
+	}
+	_, err = mapping.ScanRows(&v, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v) != 2 || v[0].Name != "Tim" || v[1].Age != 36.5 {
+		t.Fatal(v)
+	}
+
+	_, err = NewNebulaJsonResultSet([]byte(`{"errors": [{"code": -1005, "message": "SemanticError"}]}`))
+	if err == nil {
+		t.Fatal("expect error")
+	}
+}
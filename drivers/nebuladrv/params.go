@@ -0,0 +1,249 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nebuladrv
+
+import (
+	"database/sql"
+	"fmt"
+	nebula "github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/xfali/lean/errors"
+	"github.com/xfali/lean/mapping"
+	"math"
+	"reflect"
+	"time"
+)
+
+var (
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+// slice2map converts params of Execute / Query to nebula parameters.
+// Supported forms:
+//
+//	a single map with string key: map[string]interface{}{"name": "Tim"}
+//	a single (pointer of) struct, the keys are taken from mapping.FieldAliasTagName tag or field name
+//	sql.NamedArg values: sql.Named("name", "Tim")
+//	key-value pairs: "name", "Tim", "age", 42
+//
+// sql.NamedArg and key-value pairs can be mixed.
+func slice2map(params ...interface{}) (map[string]interface{}, error) {
+	if len(params) == 1 {
+		v := reflect.ValueOf(params[0])
+		for v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Struct {
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Map:
+			return map2params(v)
+		case reflect.Struct:
+			if _, ok := params[0].(sql.NamedArg); !ok {
+				return struct2params(v)
+			}
+		}
+	}
+
+	pm := make(map[string]interface{}, len(params)>>1)
+	for i := 0; i < len(params); i++ {
+		if na, ok := params[i].(sql.NamedArg); ok {
+			if na.Name == "" {
+				return nil, fmt.Errorf("%w: named arg at index %d without name ", errors.ParamsMalformedError, i)
+			}
+			if err := putParam(pm, na.Name, na.Value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		k, ok := params[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: key [%v] at index %d ", errors.ParamsKeyTypeError, params[i], i)
+		}
+		if i+1 >= len(params) {
+			return nil, fmt.Errorf("%w: key [%s] without value ", errors.ParamsMalformedError, k)
+		}
+		i++
+		if err := putParam(pm, k, params[i]); err != nil {
+			return nil, err
+		}
+	}
+	return pm, nil
+}
+
+func putParam(pm map[string]interface{}, k string, v interface{}) error {
+	if _, ok := pm[k]; ok {
+		return fmt.Errorf("%w: duplicate key [%s] ", errors.ParamsMalformedError, k)
+	}
+	nv, err := toNebulaValue(reflect.ValueOf(v))
+	if err != nil {
+		return fmt.Errorf("%w: key [%s] %v ", errors.ParamsValueTypeError, k, err)
+	}
+	pm[k] = *nv
+	return nil
+}
+
+func map2params(v reflect.Value) (map[string]interface{}, error) {
+	if v.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("%w: map key type %s ", errors.ParamsKeyTypeError, v.Type().Key().String())
+	}
+	pm := make(map[string]interface{}, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		if err := putParam(pm, iter.Key().String(), iter.Value().Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return pm, nil
+}
+
+func struct2params(v reflect.Value) (map[string]interface{}, error) {
+	t := v.Type()
+	pm := make(map[string]interface{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tn, ok := f.Tag.Lookup(mapping.FieldAliasTagName); ok {
+			name = tn
+		}
+		if err := putParam(pm, name, v.Field(i).Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return pm, nil
+}
+
+func toNebulaValue(v reflect.Value) (*nebula.Value, error) {
+	ret := nebula.NewValue()
+	if !v.IsValid() {
+		null := nebula.NullType___NULL__
+		ret.NVal = &null
+		return ret, nil
+	}
+
+	switch o := v.Interface().(type) {
+	case nebula.Value:
+		return &o, nil
+	case *nebula.Value:
+		if o != nil {
+			return o, nil
+		}
+	case nebula.Date:
+		ret.DVal = &o
+		return ret, nil
+	case nebula.Time:
+		ret.TVal = &o
+		return ret, nil
+	case nebula.DateTime:
+		ret.DtVal = &o
+		return ret, nil
+	case nebula.Duration:
+		ret.DuVal = &o
+		return ret, nil
+	case nebula.Geography:
+		ret.GgVal = &o
+		return ret, nil
+	case time.Time:
+		u := o.UTC()
+		ret.DtVal = &nebula.DateTime{
+			Year:     int16(u.Year()),
+			Month:    int8(u.Month()),
+			Day:      int8(u.Day()),
+			Hour:     int8(u.Hour()),
+			Minute:   int8(u.Minute()),
+			Sec:      int8(u.Second()),
+			Microsec: int32(u.Nanosecond() / 1000),
+		}
+		return ret, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return toNebulaValue(reflect.Value{})
+		}
+		return toNebulaValue(v.Elem())
+	case reflect.Bool:
+		b := v.Bool()
+		ret.BVal = &b
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := v.Int()
+		ret.IVal = &i
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("value %d overflow int64 ", u)
+		}
+		i := int64(u)
+		ret.IVal = &i
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		ret.FVal = &f
+	case reflect.String:
+		ret.SVal = []byte(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Type() == bytesType {
+			ret.SVal = append([]byte(nil), v.Bytes()...)
+			return ret, nil
+		}
+		l := &nebula.NList{Values: make([]*nebula.Value, v.Len())}
+		for i := 0; i < v.Len(); i++ {
+			nv, err := toNebulaValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			l.Values[i] = nv
+		}
+		ret.LVal = l
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key type %s not string ", v.Type().Key().String())
+		}
+		m := &nebula.NMap{Kvs: make(map[string]*nebula.Value, v.Len())}
+		iter := v.MapRange()
+		for iter.Next() {
+			nv, err := toNebulaValue(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			m.Kvs[iter.Key().String()] = nv
+		}
+		ret.MVal = m
+	case reflect.Struct:
+		t := v.Type()
+		m := &nebula.NMap{Kvs: make(map[string]*nebula.Value, t.NumField())}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := f.Name
+			if tn, ok := f.Tag.Lookup(mapping.FieldAliasTagName); ok {
+				name = tn
+			}
+			nv, err := toNebulaValue(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			m.Kvs[name] = nv
+		}
+		ret.MVal = m
+	default:
+		return nil, fmt.Errorf("type %s ", v.Type().String())
+	}
+	return ret, nil
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nebuladrv
+
+import (
+	"database/sql"
+	stderrors "errors"
+	nebula "github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/xfali/lean/errors"
+	"testing"
+	"time"
+)
+
+func TestSlice2map(t *testing.T) {
+	now := time.Date(2023, 4, 5, 6, 7, 8, 9000, time.Local)
+	t.Run("pairs", func(t *testing.T) {
+		pm, err := slice2map("name", "Tim", sql.Named("age", int64(42)), "tags", []string{"a", "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pm) != 3 {
+			t.Fatal(pm)
+		}
+		if v := toValue(t, pm["age"]); *v.IVal != 42 {
+			t.Fatal(v)
+		}
+		if v := toValue(t, pm["tags"]); len(v.LVal.Values) != 2 || string(v.LVal.Values[1].SVal) != "b" {
+			t.Fatal(v)
+		}
+	})
+	t.Run("map", func(t *testing.T) {
+		pm, err := slice2map(map[string]interface{}{
+			"time":  now,
+			"props": map[string]interface{}{"score": 1.5, "nil": nil},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		dt := toValue(t, pm["time"]).DtVal
+		u := now.UTC()
+		if dt == nil || int(dt.Hour) != u.Hour() || dt.Microsec != 9 {
+			t.Fatal(dt)
+		}
+		m := toValue(t, pm["props"]).MVal
+		if *m.Kvs["score"].FVal != 1.5 || m.Kvs["nil"].NVal == nil {
+			t.Fatal(m)
+		}
+	})
+	t.Run("struct", func(t *testing.T) {
+		pm, err := slice2map(&struct {
+			Name string `column:"name"`
+			Age  uint8
+		}{Name: "Tim", Age: 42})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(toValue(t, pm["name"]).SVal) != "Tim" || *toValue(t, pm["Age"]).IVal != 42 {
+			t.Fatal(pm)
+		}
+	})
+	t.Run("malformed", func(t *testing.T) {
+		if _, err := slice2map("name", "Tim", "age"); !stderrors.Is(err, errors.ParamsMalformedError) {
+			t.Fatal(err)
+		}
+		if _, err := slice2map(1, "Tim"); !stderrors.Is(err, errors.ParamsKeyTypeError) {
+			t.Fatal(err)
+		}
+		if _, err := slice2map("ch", make(chan int)); !stderrors.Is(err, errors.ParamsValueTypeError) {
+			t.Fatal(err)
+		}
+	})
+}
+
+func toValue(t *testing.T, v interface{}) *nebula.Value {
+	nv, ok := v.(nebula.Value)
+	if !ok {
+		t.Fatalf("expect nebula.Value but get %T", v)
+	}
+	return &nv
+}
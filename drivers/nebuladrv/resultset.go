@@ -20,11 +20,32 @@ import (
 	"errors"
 	"fmt"
 	nebula "github.com/vesoft-inc/nebula-go/v3"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/graph"
 	"github.com/xfali/reflection"
 	"reflect"
 	"time"
 )
 
+// ResultSetEx exposes nebula specific information of the result returned by nebula session,
+// use type assertion to get it:
+//
+//	if ex, ok := ret.(nebuladrv.ResultSetEx); ok {
+//		latency := ex.GetLatency()
+//	}
+type ResultSetEx interface {
+	// GetLatency returns latency of the statement in microseconds.
+	GetLatency() int64
+
+	GetPlanDesc() *graph.PlanDescription
+
+	GetSpaceName() string
+
+	GetComment() string
+
+	// GetResultSet returns the raw nebula result, nil if the statement executed in json mode.
+	GetResultSet() *nebula.ResultSet
+}
+
 type nebulaResultSet struct {
 	rs    *nebula.ResultSet
 	index int
@@ -76,6 +97,26 @@ func (r *nebulaResultSet) RowsAffected() (int64, error) {
 	return 0, errors.New("Not support ")
 }
 
+func (r *nebulaResultSet) GetLatency() int64 {
+	return r.rs.GetLatency()
+}
+
+func (r *nebulaResultSet) GetPlanDesc() *graph.PlanDescription {
+	return r.rs.GetPlanDesc()
+}
+
+func (r *nebulaResultSet) GetSpaceName() string {
+	return r.rs.GetSpaceName()
+}
+
+func (r *nebulaResultSet) GetComment() string {
+	return r.rs.GetComment()
+}
+
+func (r *nebulaResultSet) GetResultSet() *nebula.ResultSet {
+	return r.rs
+}
+
 func set2Value(dest interface{}, value *nebula.ValueWrapper) error {
 	if dst, ok := dest.(*interface{}); ok {
 		if value.IsNull() {
@@ -18,20 +18,119 @@ package nebuladrv
 
 import (
 	"context"
-	"errors"
+	stderrors "errors"
 	"fmt"
 	nebula "github.com/vesoft-inc/nebula-go/v3"
+	"github.com/xfali/lean/errors"
 	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/statement"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type SessionOpt func(*nebulaSession)
+
+// DefaultCloseTimeout bounds the wait of Close for the statement abandoned by timeout.
+const DefaultCloseTimeout = 10 * time.Second
+
+// TxQueryPolicy decides how Query behaves between Begin and Commit / Rollback when transaction emulation is enabled.
+type TxQueryPolicy int
+
+const (
+	// TxQueryPassThrough executes the query immediately, buffered statements are invisible to it.
+	TxQueryPassThrough TxQueryPolicy = iota
+	// TxQueryReject returns errors.TransactionQueryRejected.
+	TxQueryReject
 )
 
 type nebulaSession struct {
-	sess *nebula.Session
+	sess     *nebula.Session
+	jsonMode bool
+
+	txEmulation bool
+	txPolicy    TxQueryPolicy
+	tx          *bufferedTx
+
+	timeout      time.Duration
+	closeTimeout time.Duration
+	// busy is held while a statement is executing, statement abandoned by timeout holds it until it returns.
+	busy chan struct{}
+
+	onClose func()
+}
+
+type bufferedTx struct {
+	stmts  []string
+	params map[string]interface{}
 }
 
-func NewNebulaSession(sess *nebula.Session) *nebulaSession {
-	return &nebulaSession{
-		sess: sess,
+// add buffers the statement. Params are renamed with the index of statement as suffix, e.g. $id of the second
+// statement becomes $id_tx1, so that statements of the transaction can use the same keys.
+func (tx *bufferedTx) add(stmt string, pm map[string]interface{}) error {
+	suffix := fmt.Sprintf("_tx%d", len(tx.stmts))
+	renamed := make(map[string]interface{}, len(pm))
+	for k, v := range pm {
+		if _, ok := tx.params[k+suffix]; ok {
+			return fmt.Errorf("%w: duplicate key [%s] in transaction ", errors.ParamsMalformedError, k+suffix)
+		}
+		renamed[k+suffix] = v
+	}
+	if len(pm) > 0 {
+		stmt = renameParams(stmt, pm, suffix)
+	}
+	for k, v := range renamed {
+		tx.params[k] = v
 	}
+	tx.stmts = append(tx.stmts, stmt)
+	return nil
+}
+
+// renameParams appends suffix to the references of params in stmt, quoted strings are not changed.
+func renameParams(stmt string, pm map[string]interface{}, suffix string) string {
+	var sb strings.Builder
+	for i := 0; i < len(stmt); i++ {
+		c := stmt[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			j := i + 1
+			for ; j < len(stmt) && stmt[j] != c; j++ {
+				if stmt[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(stmt) {
+				j = len(stmt) - 1
+			}
+			sb.WriteString(stmt[i : j+1])
+			i = j
+		case c == '$':
+			j := i + 1
+			for j < len(stmt) && (stmt[j] == '_' || unicode.IsLetter(rune(stmt[j])) || unicode.IsDigit(rune(stmt[j]))) {
+				j++
+			}
+			sb.WriteString(stmt[i:j])
+			if _, ok := pm[stmt[i+1:j]]; ok && j > i+1 {
+				sb.WriteString(suffix)
+			}
+			i = j - 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+func NewNebulaSession(sess *nebula.Session, opts ...SessionOpt) *nebulaSession {
+	ret := &nebulaSession{
+		sess:         sess,
+		busy:         make(chan struct{}, 1),
+		closeTimeout: DefaultCloseTimeout,
+	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
 }
 
 func (s *nebulaSession) Ping(ctx context.Context) bool {
@@ -39,19 +138,93 @@ func (s *nebulaSession) Ping(ctx context.Context) bool {
 }
 
 func (s *nebulaSession) Query(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
-	return s.Execute(ctx, stmt, params...)
+	if s.tx != nil && s.txPolicy == TxQueryReject {
+		return nil, errors.TransactionQueryRejected
+	}
+	pm, err := s.params(params...)
+	if err != nil {
+		return nil, err
+	}
+	return s.execute(ctx, stmt, pm)
 }
 
+// Execute buffers the statement if transaction emulation is enabled and a transaction has begun,
+// the returned result is empty. Params of buffered statements are renamed, see bufferedTx.add.
 func (s *nebulaSession) Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	pm, err := s.params(params...)
+	if err != nil {
+		return nil, err
+	}
+	if s.tx != nil {
+		if err := s.tx.add(stmt, pm); err != nil {
+			return nil, err
+		}
+		return resultset.NewSliceResult[interface{}](nil, nil, resultset.InterfaceSetter), nil
+	}
+	return s.execute(ctx, stmt, pm)
+}
+
+// ExecuteBatch executes the statement with every params set, statements are buffered if transaction emulation
+// is enabled and a transaction has begun.
+func (s *nebulaSession) ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error) {
+	return statement.RunBatch(ctx, params, func(ctx context.Context, params ...interface{}) (resultset.Result, error) {
+		return s.Execute(ctx, stmt, params...)
+	})
+}
+
+func (s *nebulaSession) params(params ...interface{}) (map[string]interface{}, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	return slice2map(params...)
+}
+
+// execute runs the statement bounded by the statement timeout. Nebula client cannot cancel a request, the request
+// abandoned by timeout keeps the session busy, following statements wait for it until their ctx is done.
+func (s *nebulaSession) execute(ctx context.Context, stmt string, pm map[string]interface{}) (resultset.Result, error) {
+	ctx, cancel := statement.WithDeadline(ctx, s.timeout)
+	defer cancel()
+	select {
+	case s.busy <- struct{}{}:
+	case <-ctx.Done():
+		return nil, statement.CheckTimeout(ctx, ctx.Err())
+	}
+	if ctx.Done() == nil {
+		defer s.idle()
+		return s.executeSync(stmt, pm)
+	}
+
+	type result struct {
+		ret resultset.Result
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		defer s.idle()
+		ret, err := s.executeSync(stmt, pm)
+		ch <- result{ret: ret, err: err}
+	}()
+	select {
+	case v := <-ch:
+		return v.ret, v.err
+	case <-ctx.Done():
+		return nil, statement.CheckTimeout(ctx, ctx.Err())
+	}
+}
+
+func (s *nebulaSession) idle() {
+	<-s.busy
+}
+
+func (s *nebulaSession) executeSync(stmt string, pm map[string]interface{}) (resultset.Result, error) {
+	if s.jsonMode {
+		return s.executeJson(stmt, pm)
+	}
 	var rs *nebula.ResultSet
 	var err error
-	if len(params) == 0 {
+	if len(pm) == 0 {
 		rs, err = s.sess.Execute(stmt)
 	} else {
-		pm, err2 := slice2map(params...)
-		if err2 != nil {
-			return nil, err2
-		}
 		rs, err = s.sess.ExecuteWithParameter(stmt, pm)
 	}
 	err = CheckResultSet(rs, err)
@@ -62,36 +235,136 @@ func (s *nebulaSession) Execute(ctx context.Context, stmt string, params ...inte
 	return NewNebulaResultSet(rs), nil
 }
 
+func (s *nebulaSession) executeJson(stmt string, pm map[string]interface{}) (resultset.Result, error) {
+	var data []byte
+	var err error
+	if len(pm) == 0 {
+		data, err = s.sess.ExecuteJson(stmt)
+	} else {
+		data, err = s.sess.ExecuteJsonWithParameter(stmt, pm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewNebulaJsonResultSet(data)
+}
+
 func (s *nebulaSession) Begin(ctx context.Context) error {
-	return errors.New("Nebula not support transaction ")
+	if !s.txEmulation {
+		return stderrors.New("Nebula not support transaction ")
+	}
+	if s.tx != nil {
+		return errors.TransactionHaveBegin
+	}
+	s.tx = &bufferedTx{
+		params: map[string]interface{}{},
+	}
+	return nil
 }
 
+// Commit sends the buffered statements in one request, joined by ';'.
+// Nebula executes them in order and stops at the first failure, statements before it are NOT rolled back.
 func (s *nebulaSession) Commit(ctx context.Context) error {
-	return errors.New("Nebula not support transaction ")
+	if !s.txEmulation {
+		return stderrors.New("Nebula not support transaction ")
+	}
+	tx := s.tx
+	if tx == nil {
+		return errors.TransactionWithoutBegin
+	}
+	s.tx = nil
+	if len(tx.stmts) == 0 {
+		return nil
+	}
+	_, err := s.execute(ctx, strings.Join(tx.stmts, "; "), tx.params)
+	if err != nil {
+		return fmt.Errorf("%w: %v ", errors.TransactionCommitError, err)
+	}
+	return nil
 }
 
 func (s *nebulaSession) Rollback(ctx context.Context) error {
-	return errors.New("Nebula not support transaction ")
+	if !s.txEmulation {
+		return stderrors.New("Nebula not support transaction ")
+	}
+	if s.tx == nil {
+		return errors.TransactionWithoutBegin
+	}
+	s.tx = nil
+	return nil
 }
 
+// Close waits for the statement abandoned by timeout before the session is released. If the statement does not
+// return in close timeout, Close returns errors.StatementTimeout and the session is released after it returns.
 func (s *nebulaSession) Close() error {
+	s.tx = nil
+	if s.closeTimeout <= 0 {
+		s.busy <- struct{}{}
+		s.release()
+		return nil
+	}
+	timer := time.NewTimer(s.closeTimeout)
+	defer timer.Stop()
+	select {
+	case s.busy <- struct{}{}:
+		s.release()
+		return nil
+	case <-timer.C:
+		go func() {
+			s.busy <- struct{}{}
+			s.release()
+		}()
+		return fmt.Errorf("%w: session is busy, it will be released after the running statement returns ", errors.StatementTimeout)
+	}
+}
+
+// release releases the session, busy must be held.
+func (s *nebulaSession) release() {
+	defer s.idle()
 	s.sess.Release()
-	return nil
+	if s.onClose != nil {
+		s.onClose()
+		s.onClose = nil
+	}
 }
 
-func slice2map(params ...interface{}) (map[string]interface{}, error) {
-	pm := make(map[string]interface{}, len(params)>>1)
-	var k string
-	for i := range params {
-		if i%2 == 0 {
-			if kk, ok := params[i].(string); ok {
-				k = kk
-			} else {
-				return nil, fmt.Errorf("Key [%v] not a string ", params[i])
-			}
-		} else {
-			pm[k] = params[i]
-		}
+type sessOpts struct{}
+
+var SessOpts sessOpts
+
+// SetJsonMode executes statements with ExecuteJson / ExecuteJsonWithParameter, results implement JsonResultSetEx.
+func (sessOpts) SetJsonMode(jsonMode bool) SessionOpt {
+	return func(session *nebulaSession) {
+		session.jsonMode = jsonMode
+	}
+}
+
+// SetTransactionEmulation enables client side transaction: statements passed to Execute after Begin are
+// buffered, Commit sends them in one request and Rollback discards them.
+func (sessOpts) SetTransactionEmulation(enable bool) SessionOpt {
+	return func(session *nebulaSession) {
+		session.txEmulation = enable
+	}
+}
+
+func (sessOpts) SetTxQueryPolicy(policy TxQueryPolicy) SessionOpt {
+	return func(session *nebulaSession) {
+		session.txPolicy = policy
+	}
+}
+
+// SetStatementTimeout sets the default timeout of Query, Execute and Commit, 0 means no timeout.
+// It can be overridden by the deadline of ctx or statement.WithTimeout.
+func (sessOpts) SetStatementTimeout(timeout time.Duration) SessionOpt {
+	return func(session *nebulaSession) {
+		session.timeout = timeout
+	}
+}
+
+// SetCloseTimeout sets the max time Close waits for the statement abandoned by timeout, default is
+// DefaultCloseTimeout, 0 means waiting until the statement returns.
+func (sessOpts) SetCloseTimeout(timeout time.Duration) SessionOpt {
+	return func(session *nebulaSession) {
+		session.closeTimeout = timeout
 	}
-	return pm, nil
 }
@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package nebuladrv
+
+import (
+	"context"
+	stderrors "errors"
+	nebula "github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/xfali/lean/errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSessionCloseTimeout(t *testing.T) {
+	closed := make(chan struct{})
+	sess := NewNebulaSession(nil, SessOpts.SetCloseTimeout(20*time.Millisecond))
+	sess.onClose = func() {
+		close(closed)
+	}
+	// statement abandoned by timeout
+	sess.busy <- struct{}{}
+
+	if err := sess.Close(); !stderrors.Is(err, errors.StatementTimeout) {
+		t.Fatal("expect StatementTimeout but get ", err)
+	}
+	select {
+	case <-closed:
+		t.Fatal("expect session released after the statement returned")
+	default:
+	}
+	sess.idle()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("expect session released")
+	}
+}
+
+func TestSessionTransactionParams(t *testing.T) {
+	sess := NewNebulaSession(nil, SessOpts.SetTransactionEmulation(true))
+	ctx := context.Background()
+	if err := sess.Begin(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.Execute(ctx, "UPDATE VERTEX ON user $id SET name = '$id'", "id", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.ExecuteBatch(ctx, "DELETE VERTEX $id", [][]interface{}{{"id", 2}, {"id", 3}}); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"UPDATE VERTEX ON user $id_tx0 SET name = '$id'",
+		"DELETE VERTEX $id_tx1",
+		"DELETE VERTEX $id_tx2",
+	}
+	if !reflect.DeepEqual(sess.tx.stmts, expect) {
+		t.Fatal("expect ", expect, " but get ", sess.tx.stmts)
+	}
+	if len(sess.tx.params) != 3 || *sess.tx.params["id_tx2"].(nebula.Value).IVal != 3 {
+		t.Fatal("unexpected params ", sess.tx.params)
+	}
+
+	if _, err := sess.Execute(ctx, "DELETE VERTEX $id", "id"); err == nil {
+		t.Fatal("expect params error")
+	}
+	if len(sess.tx.stmts) != 3 || len(sess.tx.params) != 3 {
+		t.Fatal("expect failed statement not buffered ", sess.tx.stmts, sess.tx.params)
+	}
+}
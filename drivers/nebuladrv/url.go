@@ -0,0 +1,139 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nebuladrv
+
+import (
+	"crypto/tls"
+	"fmt"
+	nebula "github.com/vesoft-inc/nebula-go/v3"
+	"github.com/xfali/lean/connection"
+	"github.com/xfali/lean/drivers"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	drivers.Register("nebula", URLFactory)
+}
+
+// URLFactory creates connection from nebula://[user:password@]host:9669,host2:9669[/space][?params], params:
+//
+//	user, password   user info if it is not in url
+//	maxConn          max connections of pool
+//	minConn          min connections of pool
+//	timeout          socket timeout, e.g. 3s
+//	idleTime         idle time of connections, e.g. 10m
+//	statementTimeout default statement timeout of sessions, e.g. 30s
+//	tls              true enables tls
+//	tlsSkipVerify    true skips verifying the certificate of server
+func URLFactory(rawURL string) (connection.Connection, error) {
+	rest := rawURL
+	if i := strings.Index(rest, "://"); i >= 0 {
+		rest = rest[i+3:]
+	}
+	query := url.Values{}
+	if i := strings.Index(rest, "?"); i >= 0 {
+		q, err := url.ParseQuery(rest[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("Parse url params failed: %v ", err)
+		}
+		query, rest = q, rest[:i]
+	}
+	space := ""
+	if i := strings.Index(rest, "/"); i >= 0 {
+		space, rest = strings.Trim(rest[i+1:], "/"), rest[:i]
+	}
+
+	username, password := query.Get("user"), query.Get("password")
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		info := rest[:i]
+		rest = rest[i+1:]
+		name, pwd, _ := strings.Cut(info, ":")
+		var err error
+		if username, err = url.PathUnescape(name); err != nil {
+			return nil, fmt.Errorf("Parse user failed: %v ", err)
+		}
+		if password, err = url.PathUnescape(pwd); err != nil {
+			return nil, fmt.Errorf("Parse password failed: %v ", err)
+		}
+	}
+
+	opts := []ConnectionOpt{
+		ConnOpts.WithUserInfo(username, password),
+		ConnOpts.SetSpace(space),
+	}
+	for _, addr := range strings.Split(rest, ",") {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("Nebula address %s invalid: %v ", addr, err)
+		}
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("Nebula address %s invalid: %v ", addr, err)
+		}
+		opts = append(opts, ConnOpts.AddAddress(host, p))
+	}
+
+	conf := nebula.GetDefaultConf()
+	for _, v := range []struct {
+		key string
+		p   *int
+	}{
+		{"maxConn", &conf.MaxConnPoolSize},
+		{"minConn", &conf.MinConnPoolSize},
+	} {
+		if s := query.Get(v.key); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, fmt.Errorf("Param %s invalid: %v ", v.key, err)
+			}
+			*v.p = n
+		}
+	}
+	for _, v := range []struct {
+		key string
+		p   *time.Duration
+	}{
+		{"timeout", &conf.TimeOut},
+		{"idleTime", &conf.IdleTime},
+	} {
+		if s := query.Get(v.key); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return nil, fmt.Errorf("Param %s invalid: %v ", v.key, err)
+			}
+			*v.p = d
+		}
+	}
+	opts = append(opts, ConnOpts.SetConnectConfig(conf))
+	if s := query.Get("statementTimeout"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("Param statementTimeout invalid: %v ", err)
+		}
+		opts = append(opts, ConnOpts.SetStatementTimeout(d))
+	}
+
+	if enable, _ := strconv.ParseBool(query.Get("tls")); enable {
+		skip, _ := strconv.ParseBool(query.Get("tlsSkipVerify"))
+		opts = append(opts, ConnOpts.SetSslConfig(&tls.Config{InsecureSkipVerify: skip}))
+	}
+	return NewNebulaConnection(opts...), nil
+}
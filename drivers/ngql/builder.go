@@ -0,0 +1,449 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ngql
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Builder generates a nGQL statement with its parameters.
+// Parameters are key-value pairs and can be passed to session.Session directly:
+//
+//	stmt, params, err := builder.Build()
+//	sess.Execute(ctx, stmt, params...)
+type Builder interface {
+	Build() (string, []interface{}, error)
+}
+
+type InsertVertexBuilder struct {
+	tag         string
+	values      []interface{}
+	ifNotExists bool
+}
+
+// InsertVertex creates INSERT VERTEX statement, values must be structs (or slice of structs) with a field tagged nebula:"vid".
+func InsertVertex(tag string, values ...interface{}) *InsertVertexBuilder {
+	return &InsertVertexBuilder{
+		tag:    tag,
+		values: flatten(values),
+	}
+}
+
+func (b *InsertVertexBuilder) IfNotExists() *InsertVertexBuilder {
+	b.ifNotExists = true
+	return b
+}
+
+func (b *InsertVertexBuilder) Build() (string, []interface{}, error) {
+	return buildInsert("VERTEX", b.tag, b.values, b.ifNotExists, object.vid)
+}
+
+type InsertEdgeBuilder struct {
+	edge        string
+	values      []interface{}
+	ifNotExists bool
+}
+
+// InsertEdge creates INSERT EDGE statement, values must be structs (or slice of structs) with fields tagged nebula:"src" and nebula:"dst", nebula:"rank" is optional.
+func InsertEdge(edge string, values ...interface{}) *InsertEdgeBuilder {
+	return &InsertEdgeBuilder{
+		edge:   edge,
+		values: flatten(values),
+	}
+}
+
+func (b *InsertEdgeBuilder) IfNotExists() *InsertEdgeBuilder {
+	b.ifNotExists = true
+	return b
+}
+
+func (b *InsertEdgeBuilder) Build() (string, []interface{}, error) {
+	return buildInsert("EDGE", b.edge, b.values, b.ifNotExists, object.edge)
+}
+
+func buildInsert(kind, name string, values []interface{}, ifNotExists bool, key func(object) (string, error)) (string, []interface{}, error) {
+	if len(values) == 0 {
+		return "", nil, errors.New("Insert values are empty ")
+	}
+	id, err := Identifier(name)
+	if err != nil {
+		return "", nil, err
+	}
+	var sb strings.Builder
+	var s *schema
+	for i, v := range values {
+		o, err := newObject(v)
+		if err != nil {
+			return "", nil, err
+		}
+		if s == nil {
+			s = o.schema
+			names, err := s.propNames()
+			if err != nil {
+				return "", nil, err
+			}
+			sb.WriteString("INSERT ")
+			sb.WriteString(kind)
+			if ifNotExists {
+				sb.WriteString(" IF NOT EXISTS")
+			}
+			sb.WriteString(" ")
+			sb.WriteString(id)
+			sb.WriteString("(")
+			sb.WriteString(strings.Join(names, ", "))
+			sb.WriteString(") VALUES ")
+		} else if s != o.schema {
+			return "", nil, fmt.Errorf("Insert values must be same type, index %d is %s ", i, o.value.Type().String())
+		}
+		k, err := key(o)
+		if err != nil {
+			return "", nil, err
+		}
+		props, err := o.propValues()
+		if err != nil {
+			return "", nil, err
+		}
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(k)
+		sb.WriteString(":(")
+		sb.WriteString(strings.Join(props, ", "))
+		sb.WriteString(")")
+	}
+	return sb.String(), nil, nil
+}
+
+type UpsertBuilder struct {
+	kind  string
+	name  string
+	value interface{}
+	when  string
+	yield string
+	key   func(object) (string, error)
+}
+
+// UpsertVertex creates UPSERT VERTEX statement which sets all properties of value.
+func UpsertVertex(tag string, value interface{}) *UpsertBuilder {
+	return &UpsertBuilder{
+		kind:  "VERTEX",
+		name:  tag,
+		value: value,
+		key:   object.vid,
+	}
+}
+
+// UpsertEdge creates UPSERT EDGE statement which sets all properties of value.
+func UpsertEdge(edge string, value interface{}) *UpsertBuilder {
+	return &UpsertBuilder{
+		kind:  "EDGE",
+		name:  edge,
+		value: value,
+		key:   object.edge,
+	}
+}
+
+// When sets the raw nGQL condition of the statement.
+func (b *UpsertBuilder) When(cond string) *UpsertBuilder {
+	b.when = cond
+	return b
+}
+
+// Yield sets the raw nGQL yield clause of the statement.
+func (b *UpsertBuilder) Yield(yield string) *UpsertBuilder {
+	b.yield = yield
+	return b
+}
+
+func (b *UpsertBuilder) Build() (string, []interface{}, error) {
+	id, err := Identifier(b.name)
+	if err != nil {
+		return "", nil, err
+	}
+	o, err := newObject(b.value)
+	if err != nil {
+		return "", nil, err
+	}
+	k, err := b.key(o)
+	if err != nil {
+		return "", nil, err
+	}
+	names, err := o.schema.propNames()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(names) == 0 {
+		return "", nil, fmt.Errorf("Type %s without property ", o.value.Type().String())
+	}
+	props, err := o.propValues()
+	if err != nil {
+		return "", nil, err
+	}
+	var sb strings.Builder
+	sb.WriteString("UPSERT ")
+	sb.WriteString(b.kind)
+	sb.WriteString(" ON ")
+	sb.WriteString(id)
+	sb.WriteString(" ")
+	sb.WriteString(k)
+	sb.WriteString(" SET ")
+	for i := range names {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(names[i])
+		sb.WriteString(" = ")
+		sb.WriteString(props[i])
+	}
+	if b.when != "" {
+		sb.WriteString(" WHEN ")
+		sb.WriteString(b.when)
+	}
+	if b.yield != "" {
+		sb.WriteString(" YIELD ")
+		sb.WriteString(b.yield)
+	}
+	return sb.String(), nil, nil
+}
+
+type DeleteVertexBuilder struct {
+	vids     []interface{}
+	withEdge bool
+}
+
+// DeleteVertex creates DELETE VERTEX statement, vids are either vid values or structs with a field tagged nebula:"vid".
+func DeleteVertex(vids ...interface{}) *DeleteVertexBuilder {
+	return &DeleteVertexBuilder{
+		vids: flatten(vids),
+	}
+}
+
+func (b *DeleteVertexBuilder) WithEdge() *DeleteVertexBuilder {
+	b.withEdge = true
+	return b
+}
+
+func (b *DeleteVertexBuilder) Build() (string, []interface{}, error) {
+	vids, err := vidList(b.vids)
+	if err != nil {
+		return "", nil, err
+	}
+	stmt := "DELETE VERTEX " + vids
+	if b.withEdge {
+		stmt += " WITH EDGE"
+	}
+	return stmt, nil, nil
+}
+
+type DeleteEdgeBuilder struct {
+	edge   string
+	values []interface{}
+}
+
+// DeleteEdge creates DELETE EDGE statement, values must be structs with fields tagged nebula:"src" and nebula:"dst".
+func DeleteEdge(edge string, values ...interface{}) *DeleteEdgeBuilder {
+	return &DeleteEdgeBuilder{
+		edge:   edge,
+		values: flatten(values),
+	}
+}
+
+func (b *DeleteEdgeBuilder) Build() (string, []interface{}, error) {
+	id, err := Identifier(b.edge)
+	if err != nil {
+		return "", nil, err
+	}
+	edges, err := edgeList(b.values)
+	if err != nil {
+		return "", nil, err
+	}
+	return "DELETE EDGE " + id + " " + edges, nil, nil
+}
+
+type FetchBuilder struct {
+	name   string
+	values []interface{}
+	yield  string
+	list   func([]interface{}) (string, error)
+}
+
+// FetchVertex creates FETCH PROP ON tag statement, vids are either vid values or structs with a field tagged nebula:"vid".
+func FetchVertex(tag string, vids ...interface{}) *FetchBuilder {
+	return &FetchBuilder{
+		name:   tag,
+		values: flatten(vids),
+		yield:  "properties(vertex)",
+		list:   vidList,
+	}
+}
+
+// FetchEdge creates FETCH PROP ON edge statement, values must be structs with fields tagged nebula:"src" and nebula:"dst".
+func FetchEdge(edge string, values ...interface{}) *FetchBuilder {
+	return &FetchBuilder{
+		name:   edge,
+		values: flatten(values),
+		yield:  "properties(edge)",
+		list:   edgeList,
+	}
+}
+
+// Yield sets the raw nGQL yield clause of the statement.
+func (b *FetchBuilder) Yield(yield string) *FetchBuilder {
+	b.yield = yield
+	return b
+}
+
+func (b *FetchBuilder) Build() (string, []interface{}, error) {
+	id, err := Identifier(b.name)
+	if err != nil {
+		return "", nil, err
+	}
+	l, err := b.list(b.values)
+	if err != nil {
+		return "", nil, err
+	}
+	return "FETCH PROP ON " + id + " " + l + " YIELD " + b.yield, nil, nil
+}
+
+type MatchBuilder struct {
+	pattern string
+	where   []string
+	ret     []string
+	orderBy []string
+	skip    int
+	limit   int
+	params  []interface{}
+}
+
+// Match creates MATCH statement with raw nGQL pattern, values should be referenced as $name in
+// the pattern or conditions and be set by Param, they are sent with ExecuteWithParameter.
+func Match(pattern string) *MatchBuilder {
+	return &MatchBuilder{
+		pattern: pattern,
+		limit:   -1,
+	}
+}
+
+// Where appends a raw nGQL condition, conditions are joined with AND.
+func (b *MatchBuilder) Where(cond string) *MatchBuilder {
+	b.where = append(b.where, cond)
+	return b
+}
+
+func (b *MatchBuilder) Param(name string, value interface{}) *MatchBuilder {
+	b.params = append(b.params, name, value)
+	return b
+}
+
+func (b *MatchBuilder) Return(items ...string) *MatchBuilder {
+	b.ret = append(b.ret, items...)
+	return b
+}
+
+func (b *MatchBuilder) OrderBy(items ...string) *MatchBuilder {
+	b.orderBy = append(b.orderBy, items...)
+	return b
+}
+
+func (b *MatchBuilder) Skip(skip int) *MatchBuilder {
+	b.skip = skip
+	return b
+}
+
+func (b *MatchBuilder) Limit(limit int) *MatchBuilder {
+	b.limit = limit
+	return b
+}
+
+func (b *MatchBuilder) Build() (string, []interface{}, error) {
+	if b.pattern == "" {
+		return "", nil, errors.New("Match pattern is empty ")
+	}
+	if len(b.ret) == 0 {
+		return "", nil, errors.New("Match without return ")
+	}
+	var sb strings.Builder
+	sb.WriteString("MATCH ")
+	sb.WriteString(b.pattern)
+	if len(b.where) > 0 {
+		sb.WriteString(" WHERE ")
+		if len(b.where) == 1 {
+			sb.WriteString(b.where[0])
+		} else {
+			sb.WriteString("(")
+			sb.WriteString(strings.Join(b.where, ") AND ("))
+			sb.WriteString(")")
+		}
+	}
+	sb.WriteString(" RETURN ")
+	sb.WriteString(strings.Join(b.ret, ", "))
+	if len(b.orderBy) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(b.orderBy, ", "))
+	}
+	if b.skip > 0 {
+		sb.WriteString(" SKIP ")
+		sb.WriteString(strconv.Itoa(b.skip))
+	}
+	if b.limit >= 0 {
+		sb.WriteString(" LIMIT ")
+		sb.WriteString(strconv.Itoa(b.limit))
+	}
+	return sb.String(), b.params, nil
+}
+
+func vidList(values []interface{}) (string, error) {
+	if len(values) == 0 {
+		return "", errors.New("Vid list is empty ")
+	}
+	ret := make([]string, len(values))
+	for i, v := range values {
+		if o, err := newObject(v); err == nil {
+			ret[i], err = o.vid()
+			if err != nil {
+				return "", err
+			}
+		} else {
+			ret[i], err = Literal(v)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	return strings.Join(ret, ", "), nil
+}
+
+func edgeList(values []interface{}) (string, error) {
+	if len(values) == 0 {
+		return "", errors.New("Edge list is empty ")
+	}
+	ret := make([]string, len(values))
+	for i, v := range values {
+		o, err := newObject(v)
+		if err != nil {
+			return "", err
+		}
+		ret[i], err = o.edge()
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(ret, ", "), nil
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ngql
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DateTimeFormat = "2006-01-02T15:04:05.000000"
+)
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+// Literal formats v as a nGQL literal, strings are quoted and escaped.
+func Literal(v interface{}) (string, error) {
+	var sb strings.Builder
+	if err := writeLiteral(&sb, reflect.ValueOf(v)); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Quote returns s as a double-quoted nGQL string literal.
+func Quote(s string) string {
+	var sb strings.Builder
+	writeString(&sb, s)
+	return sb.String()
+}
+
+// Identifier returns name wrapped in backquotes, it can be used as tag, edge or property name.
+func Identifier(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("Identifier is empty ")
+	}
+	if strings.ContainsAny(name, "`\r\n\x00") {
+		return "", fmt.Errorf("Identifier [%s] contains illegal character ", name)
+	}
+	return "`" + name + "`", nil
+}
+
+func writeLiteral(sb *strings.Builder, v reflect.Value) error {
+	if !v.IsValid() {
+		sb.WriteString("NULL")
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			sb.WriteString("NULL")
+			return nil
+		}
+		return writeLiteral(sb, v.Elem())
+	case reflect.Bool:
+		sb.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		sb.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return fmt.Errorf("Value %d overflow nebula int64 ", u)
+		}
+		sb.WriteString(strconv.FormatUint(u, 10))
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("Not support float value %v ", f)
+		}
+		s := strconv.FormatFloat(f, 'f', -1, 64)
+		if !strings.ContainsAny(s, ".") {
+			s += ".0"
+		}
+		sb.WriteString(s)
+	case reflect.String:
+		writeString(sb, v.String())
+	case reflect.Struct:
+		if v.Type() == timeType {
+			t := v.Interface().(time.Time)
+			sb.WriteString("datetime(")
+			writeString(sb, t.UTC().Format(DateTimeFormat))
+			sb.WriteString(")")
+			return nil
+		}
+		return fmt.Errorf("Not support literal type [%s] ", v.Type().String())
+	case reflect.Slice, reflect.Array:
+		if v.Type() == bytesType {
+			writeString(sb, string(v.Bytes()))
+			return nil
+		}
+		sb.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if err := writeLiteral(sb, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		sb.WriteString("]")
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("Map key must be string but get [%s] ", v.Type().Key().String())
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		sb.WriteString("{")
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			id, err := Identifier(k.String())
+			if err != nil {
+				return err
+			}
+			sb.WriteString(id)
+			sb.WriteString(": ")
+			if err := writeLiteral(sb, v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+		sb.WriteString("}")
+	default:
+		return fmt.Errorf("Not support literal type [%s] ", v.Type().String())
+	}
+	return nil
+}
+
+func writeString(sb *strings.Builder, s string) {
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		default:
+			if r < 0x20 {
+				sb.WriteString(fmt.Sprintf(`\u%04x`, r))
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+}
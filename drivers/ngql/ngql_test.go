@@ -0,0 +1,141 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ngql
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type player struct {
+	Id   string `nebula:"vid"`
+	Name string `column:"name"`
+	Age  int    `column:"age"`
+}
+
+type follow struct {
+	Src    string  `nebula:"src"`
+	Dst    string  `nebula:"dst"`
+	Rank   int64   `nebula:"rank"`
+	Degree float64 `column:"degree"`
+}
+
+func TestLiteral(t *testing.T) {
+	cases := []struct {
+		v      interface{}
+		expect string
+	}{
+		{nil, "NULL"},
+		{true, "true"},
+		{int64(-3), "-3"},
+		{1.5, "1.5"},
+		{float32(2), "2.0"},
+		{`a"b\c` + "\n", `"a\"b\\c\n"`},
+		{[]interface{}{1, "x"}, `[1, "x"]`},
+		{map[string]interface{}{"b": 1, "a": "x"}, "{`a`: \"x\", `b`: 1}"},
+		{time.Date(2023, 1, 2, 3, 4, 5, 6000, time.UTC), `datetime("2023-01-02T03:04:05.000006")`},
+	}
+	for _, c := range cases {
+		s, err := Literal(c.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != c.expect {
+			t.Fatalf("expect %s but get %s", c.expect, s)
+		}
+	}
+
+	if _, err := Literal(struct{}{}); err == nil {
+		t.Fatal("expect error")
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	check := func(t *testing.T, b Builder, expect string) {
+		s, _, err := b.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != expect {
+			t.Fatalf("expect %s but get %s", expect, s)
+		}
+	}
+	t.Run("insert vertex", func(t *testing.T) {
+		check(t, InsertVertex("player", []player{{"p1", `Tim "T"`, 42}, {"p2", "Tony", 36}}).IfNotExists(),
+			"INSERT VERTEX IF NOT EXISTS `player`(`name`, `age`) VALUES \"p1\":(\"Tim \\\"T\\\"\", 42), \"p2\":(\"Tony\", 36)")
+	})
+	t.Run("insert edge", func(t *testing.T) {
+		check(t, InsertEdge("follow", &follow{"p1", "p2", 1, 95}),
+			"INSERT EDGE `follow`(`degree`) VALUES \"p1\"->\"p2\"@1:(95.0)")
+	})
+	t.Run("upsert", func(t *testing.T) {
+		check(t, UpsertVertex("player", player{"p1", "Tim", 43}).When("$^.player.age < 43"),
+			"UPSERT VERTEX ON `player` \"p1\" SET `name` = \"Tim\", `age` = 43 WHEN $^.player.age < 43")
+	})
+	t.Run("delete", func(t *testing.T) {
+		check(t, DeleteVertex("p1", player{Id: "p2"}).WithEdge(), "DELETE VERTEX \"p1\", \"p2\" WITH EDGE")
+		check(t, DeleteEdge("follow", follow{Src: "p1", Dst: "p2"}), "DELETE EDGE `follow` \"p1\"->\"p2\"@0")
+	})
+	t.Run("fetch", func(t *testing.T) {
+		check(t, FetchVertex("player", "p1"), "FETCH PROP ON `player` \"p1\" YIELD properties(vertex)")
+	})
+	t.Run("match", func(t *testing.T) {
+		b := Match("(v:player)").Where("v.player.name == $name").Param("name", "Tim").Return("v").Limit(3)
+		s, params, err := b.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != "MATCH (v:player) WHERE v.player.name == $name RETURN v LIMIT 3" {
+			t.Fatal(s)
+		}
+		if len(params) != 2 || params[0] != "name" || params[1] != "Tim" {
+			t.Fatal(params)
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		if _, _, err := InsertVertex("play`er", player{}).Build(); err == nil {
+			t.Fatal("expect error")
+		}
+		if _, _, err := InsertVertex("player", player{}, follow{}).Build(); err == nil {
+			t.Fatal("expect error")
+		}
+	})
+}
+
+func TestSchemaConcurrent(t *testing.T) {
+	type team struct {
+		Id   string `nebula:"vid"`
+		Name string `column:"name"`
+	}
+	var wait sync.WaitGroup
+	ret := make([]*schema, 8)
+	for i := range ret {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			ret[i], _ = getSchema(reflect.TypeOf(team{}))
+		}(i)
+	}
+	wait.Wait()
+	for _, v := range ret {
+		if v == nil || v != ret[0] {
+			t.Fatal("expect the same schema ", ret)
+		}
+	}
+}
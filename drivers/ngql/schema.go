@@ -0,0 +1,206 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ngql
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	PropertyTagName = "column"
+	RoleTagName     = "nebula"
+)
+
+const (
+	RoleVid  = "vid"
+	RoleSrc  = "src"
+	RoleDst  = "dst"
+	RoleRank = "rank"
+	RoleSkip = "-"
+)
+
+type property struct {
+	name  string
+	index []int
+}
+
+type schema struct {
+	vid   []int
+	src   []int
+	dst   []int
+	rank  []int
+	props []property
+}
+
+var schemaCache sync.Map
+
+func getSchema(t reflect.Type) (*schema, error) {
+	if v, ok := schemaCache.Load(t); ok {
+		return v.(*schema), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Expect struct but get %s ", t.String())
+	}
+	s := &schema{}
+	if err := parseFields(s, t, nil); err != nil {
+		return nil, err
+	}
+	// the schema built concurrently is dropped, all callers get the same schema
+	v, _ := schemaCache.LoadOrStore(t, s)
+	return v.(*schema), nil
+}
+
+func parseFields(s *schema, t reflect.Type, parent []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Type != timeType {
+			if _, ok := f.Tag.Lookup(PropertyTagName); !ok {
+				if err := parseFields(s, f.Type, index); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		switch f.Tag.Get(RoleTagName) {
+		case RoleSkip:
+			continue
+		case RoleVid:
+			s.vid = index
+			continue
+		case RoleSrc:
+			s.src = index
+			continue
+		case RoleDst:
+			s.dst = index
+			continue
+		case RoleRank:
+			s.rank = index
+			continue
+		case "":
+		default:
+			return fmt.Errorf("Field %s.%s with unknown %s tag [%s] ", t.String(), f.Name, RoleTagName, f.Tag.Get(RoleTagName))
+		}
+		name := f.Name
+		if tn, ok := f.Tag.Lookup(PropertyTagName); ok {
+			if tn == RoleSkip {
+				continue
+			}
+			name = tn
+		}
+		s.props = append(s.props, property{name: name, index: index})
+	}
+	return nil
+}
+
+func (s *schema) propNames() ([]string, error) {
+	ret := make([]string, len(s.props))
+	for i, p := range s.props {
+		id, err := Identifier(p.name)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = id
+	}
+	return ret, nil
+}
+
+type object struct {
+	value  reflect.Value
+	schema *schema
+}
+
+func newObject(o interface{}) (object, error) {
+	v := reflect.ValueOf(o)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return object{}, fmt.Errorf("Object is nil ")
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return object{}, fmt.Errorf("Object is nil ")
+	}
+	s, err := getSchema(v.Type())
+	if err != nil {
+		return object{}, err
+	}
+	return object{value: v, schema: s}, nil
+}
+
+func flatten(values []interface{}) []interface{} {
+	ret := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			for i := 0; i < rv.Len(); i++ {
+				ret = append(ret, rv.Index(i).Interface())
+			}
+		} else {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func (o object) field(index []int, role string) (string, error) {
+	if index == nil {
+		return "", fmt.Errorf("Type %s without field tagged %s:\"%s\" ", o.value.Type().String(), RoleTagName, role)
+	}
+	return Literal(o.value.FieldByIndex(index).Interface())
+}
+
+func (o object) vid() (string, error) {
+	return o.field(o.schema.vid, RoleVid)
+}
+
+// edge returns edge key: src->dst[@rank].
+func (o object) edge() (string, error) {
+	src, err := o.field(o.schema.src, RoleSrc)
+	if err != nil {
+		return "", err
+	}
+	dst, err := o.field(o.schema.dst, RoleDst)
+	if err != nil {
+		return "", err
+	}
+	if o.schema.rank == nil {
+		return src + "->" + dst, nil
+	}
+	rank, err := o.field(o.schema.rank, RoleRank)
+	if err != nil {
+		return "", err
+	}
+	return src + "->" + dst + "@" + rank, nil
+}
+
+func (o object) propValues() ([]string, error) {
+	ret := make([]string, len(o.schema.props))
+	for i, p := range o.schema.props {
+		l, err := Literal(o.value.FieldByIndex(p.index).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("Property %s: %v ", p.name, err)
+		}
+		ret[i] = l
+	}
+	return ret, nil
+}
@@ -20,12 +20,23 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/xfali/lean/connection"
+	"github.com/xfali/lean/handler"
 	"github.com/xfali/lean/session"
+	"github.com/xfali/lean/transaction"
 	"time"
 )
 
 type ConnOpt func(*sqlConnection)
 
+type ExecutorType int
+
+const (
+	ExecutorSimple ExecutorType = iota
+	// ExecutorPrepare prepares statements and caches them in a pool shared by all sessions of the connection.
+	ExecutorPrepare
+)
+
 type sqlConnection struct {
 	db             *sql.DB
 	driverName     string
@@ -36,6 +47,10 @@ type sqlConnection struct {
 	connMaxIdleTime time.Duration
 	connMaxLifetime time.Duration
 
+	execType ExecutorType
+	stmtPool handler.SharedStatementPool
+	timeout  time.Duration
+
 	sessOpts []SessionOpt
 }
 
@@ -62,23 +77,60 @@ func (c *sqlConnection) Open() error {
 	db.SetConnMaxIdleTime(c.connMaxIdleTime)
 	db.SetConnMaxLifetime(c.connMaxLifetime)
 	c.db = db
+	if c.execType == ExecutorPrepare && c.stmtPool == nil {
+		c.stmtPool = handler.NewLRUPool(handler.DefaultLRUPoolCapacity)
+	}
 	return nil
 }
 
 func (c *sqlConnection) GetSession() (session.Session, error) {
+	return c.GetWrappedSession(nil)
+}
+
+// GetWrappedSession returns a session whose transaction is wrapped by wrapper,
+// wrapper is ignored if the ExecutorFactory is replaced by SetCreateSessionOpts.
+func (c *sqlConnection) GetWrappedSession(wrapper transaction.Wrapper) (session.Session, error) {
 	if c.db == nil {
 		return nil, errors.New("Connection not opened ")
 	}
-	return NewSqlSession(c.db, c.sessOpts...), nil
+	opts := c.sessOpts
+	if c.timeout > 0 {
+		opts = append([]SessionOpt{SessOpts.SetStatementTimeout(c.timeout)}, opts...)
+	}
+	if c.execType == ExecutorPrepare || wrapper != nil {
+		opts = append([]SessionOpt{SessOpts.SetExecutorFactory(newExecutorFactory(c.stmtPool, wrapper))}, opts...)
+	}
+	return NewSqlSession(c.db, opts...), nil
 }
 
 func (c *sqlConnection) Close() error {
+	if c.stmtPool != nil {
+		c.stmtPool.PurgeAll()
+	}
 	if c.db != nil {
 		return c.db.Close()
 	}
 	return nil
 }
 
+func (c *sqlConnection) PoolStats() connection.PoolStats {
+	if c.db == nil {
+		return connection.PoolStats{}
+	}
+	stats := c.db.Stats()
+	return connection.PoolStats{
+		Up:                true,
+		MaxOpen:           stats.MaxOpenConnections,
+		Open:              stats.OpenConnections,
+		InUse:             stats.InUse,
+		Idle:              stats.Idle,
+		WaitCount:         stats.WaitCount,
+		WaitDuration:      stats.WaitDuration,
+		MaxIdleClosed:     stats.MaxIdleClosed,
+		MaxLifetimeClosed: stats.MaxLifetimeClosed,
+	}
+}
+
 type connOpts struct {
 }
 
@@ -90,6 +142,20 @@ func (o connOpts) SetCreateSessionOpts(opts ...SessionOpt) ConnOpt {
 	}
 }
 
+func (o connOpts) SetExecutorType(execType ExecutorType) ConnOpt {
+	return func(connection *sqlConnection) {
+		connection.execType = execType
+	}
+}
+
+// SetStatementPool sets the statement pool shared by sessions and enables ExecutorPrepare.
+func (o connOpts) SetStatementPool(pool handler.SharedStatementPool) ConnOpt {
+	return func(connection *sqlConnection) {
+		connection.execType = ExecutorPrepare
+		connection.stmtPool = pool
+	}
+}
+
 func (o connOpts) SetMaxConn(maxConn int) ConnOpt {
 	return func(connection *sqlConnection) {
 		connection.maxConn = maxConn
@@ -113,3 +179,10 @@ func (o connOpts) SetConnMaxLifetime(connMaxLifetime time.Duration) ConnOpt {
 		connection.connMaxLifetime = connMaxLifetime
 	}
 }
+
+// SetStatementTimeout sets the default statement timeout of sessions, see SessOpts.SetStatementTimeout.
+func (o connOpts) SetStatementTimeout(timeout time.Duration) ConnOpt {
+	return func(connection *sqlConnection) {
+		connection.timeout = timeout
+	}
+}
@@ -19,7 +19,9 @@ package sqldrv
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"github.com/xfali/lean/errors"
+	"github.com/xfali/lean/handler"
 	"github.com/xfali/lean/resultset"
 	"github.com/xfali/lean/statement"
 )
@@ -53,20 +55,33 @@ func (conn *defaultHandler) Execute(ctx context.Context, stmt string, params ...
 	return NewSqlExecResultSet(r), nil
 }
 
-type transactionHandler sql.Tx
+type transactionHandler struct {
+	tx *sql.Tx
+	db *sql.DB
+}
+
+func (transHandler *transactionHandler) Parent() handler.Handler {
+	return (*defaultHandler)(transHandler.db)
+}
+
+func (transHandler *transactionHandler) Bind(ctx context.Context, stmt statement.Statement) (statement.Statement, error) {
+	s, ok := stmt.(*sqlStatement)
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot bind statement type %T ", errors.ConnectionPrepareError, stmt)
+	}
+	return newTransactionStatement(transHandler.tx.StmtContext(ctx, (*sql.Stmt)(s))), nil
+}
 
 func (transHandler *transactionHandler) Prepare(ctx context.Context, sqlStr string) (statement.Statement, error) {
-	tx := (*sql.Tx)(transHandler)
-	stmt, err := tx.PrepareContext(ctx, sqlStr)
+	stmt, err := transHandler.tx.PrepareContext(ctx, sqlStr)
 	if err != nil {
 		return nil, errors.ConnectionPrepareError.Format(err)
 	}
-	return (*sqlStatement)(stmt), nil
+	return newTransactionStatement(stmt), nil
 }
 
 func (transHandler *transactionHandler) Query(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
-	db := (*sql.Tx)(transHandler)
-	rows, err := db.QueryContext(ctx, stmt, params...)
+	rows, err := transHandler.tx.QueryContext(ctx, stmt, params...)
 	if err != nil {
 		return nil, errors.HandlerQueryError.Format(err)
 	}
@@ -74,8 +89,7 @@ func (transHandler *transactionHandler) Query(ctx context.Context, stmt string,
 }
 
 func (transHandler *transactionHandler) Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
-	db := (*sql.Tx)(transHandler)
-	ret, err := db.ExecContext(ctx, stmt, params...)
+	ret, err := transHandler.tx.ExecContext(ctx, stmt, params...)
 	if err != nil {
 		return nil, errors.HandlerExecuteError.Format(err)
 	}
@@ -28,7 +28,7 @@ import (
 
 type defaultTransaction struct {
 	db *sql.DB
-	tx *sql.Tx
+	tx *transactionHandler
 
 	state  int32
 	locker sync.Mutex
@@ -49,7 +49,7 @@ func (trans *defaultTransaction) GetHandler() handler.Handler {
 	if trans.tx == nil {
 		return (*defaultHandler)(trans.db)
 	} else {
-		return (*transactionHandler)(trans.tx)
+		return trans.tx
 	}
 }
 
@@ -70,11 +70,15 @@ func (trans *defaultTransaction) Begin(ctx context.Context, successCallback func
 	if err != nil {
 		return errors.TransactionBeginError.Format(err)
 	}
+	txHandler := &transactionHandler{
+		tx: tx,
+		db: trans.db,
+	}
 	trans.locker.Lock()
-	trans.tx = tx
+	trans.tx = txHandler
 	trans.locker.Unlock()
 	if successCallback != nil {
-		return successCallback((*transactionHandler)(trans.tx))
+		return successCallback(txHandler)
 	}
 	return nil
 }
@@ -99,13 +103,16 @@ func (trans *defaultTransaction) Commit(ctx context.Context, successCallback fun
 			panic(o)
 		}
 	}()
-	err := tx.Commit()
+	err := tx.tx.Commit()
 	if err != nil {
 		atomic.StoreInt32(&trans.state, transaction.StateBegin)
 		return errors.TransactionCommitError.Format(err)
 	} else {
+		trans.locker.Lock()
+		trans.tx = nil
+		trans.locker.Unlock()
 		if successCallback != nil {
-			err = successCallback((*transactionHandler)(trans.tx))
+			err = successCallback(tx)
 		}
 		atomic.StoreInt32(&trans.state, transaction.StateUnknown)
 		return err
@@ -132,13 +139,20 @@ func (trans *defaultTransaction) Rollback(ctx context.Context, successCallback f
 			panic(o)
 		}
 	}()
-	err := tx.Rollback()
+	err := tx.tx.Rollback()
+	// the transaction is already finished by a failed Commit, just resets the state
+	if err == sql.ErrTxDone {
+		err = nil
+	}
 	if err != nil {
 		atomic.StoreInt32(&trans.state, transaction.StateBegin)
 		return errors.TransactionRollbackError.Format(err)
 	} else {
+		trans.locker.Lock()
+		trans.tx = nil
+		trans.locker.Unlock()
 		if successCallback != nil {
-			err = successCallback((*transactionHandler)(trans.tx))
+			err = successCallback(tx)
 		}
 		atomic.StoreInt32(&trans.state, transaction.StateUnknown)
 		return err
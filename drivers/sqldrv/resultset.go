@@ -17,8 +17,10 @@
 package sqldrv
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"github.com/xfali/lean/resultset"
 )
 
 type sqlQueryResultSet struct {
@@ -91,3 +93,21 @@ func (r *sqlExecResultSet) LastInsertId() (int64, error) {
 func (r *sqlExecResultSet) RowsAffected() (int64, error) {
 	return r.ret.RowsAffected()
 }
+
+// cancelResultSet cancels the context of query when it is closed.
+type cancelResultSet struct {
+	resultset.Result
+	cancel context.CancelFunc
+}
+
+func newCancelResultSet(ret resultset.Result, cancel context.CancelFunc) *cancelResultSet {
+	return &cancelResultSet{
+		Result: ret,
+		cancel: cancel,
+	}
+}
+
+func (r *cancelResultSet) Close() error {
+	defer r.cancel()
+	return r.Result.Close()
+}
@@ -20,7 +20,10 @@ import (
 	"context"
 	"database/sql"
 	"github.com/xfali/lean/executor"
+	"github.com/xfali/lean/handler"
 	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/statement"
+	"github.com/xfali/lean/transaction"
 	"time"
 )
 
@@ -28,14 +31,21 @@ type ExecutorFactory func(db *sql.DB) (executor.Executor, error)
 
 type SessionOpt func(*sqlSession)
 
+// DefaultCloseTimeout bounds the rollback of Close.
+const DefaultCloseTimeout = 10 * time.Second
+
 type sqlSession struct {
 	exec    executor.Executor
 	execFac ExecutorFactory
+
+	timeout      time.Duration
+	closeTimeout time.Duration
 }
 
 func NewSqlSession(db *sql.DB, opts ...SessionOpt) *sqlSession {
 	ret := &sqlSession{
-		execFac: defaultExecutorFactory,
+		execFac:      defaultExecutorFactory,
+		closeTimeout: DefaultCloseTimeout,
 	}
 	for _, opt := range opts {
 		opt(ret)
@@ -54,16 +64,54 @@ func defaultExecutorFactory(db *sql.DB) (executor.Executor, error) {
 	return exec, nil
 }
 
+// PrepareExecutorFactory creates executors which prepare statements and cache them in the shared pool.
+func PrepareExecutorFactory(pool handler.SharedStatementPool) ExecutorFactory {
+	return newExecutorFactory(pool, nil)
+}
+
+// newExecutorFactory creates PrepareExecutor if pool is not nil, otherwise SimpleExecutor.
+// The transaction of executor is wrapped by wrapper if it is not nil.
+func newExecutorFactory(pool handler.SharedStatementPool, wrapper transaction.Wrapper) ExecutorFactory {
+	return func(db *sql.DB) (executor.Executor, error) {
+		var tx transaction.Transaction = NewDefaultTransaction(db)
+		if wrapper != nil {
+			tx = wrapper(tx)
+		}
+		if pool != nil {
+			return executor.NewSharedPrepareExecutor(tx, pool), nil
+		}
+		return executor.NewSimpleExecutor(tx), nil
+	}
+}
+
 func (s *sqlSession) Ping(ctx context.Context) bool {
 	return s.exec.Ping(ctx)
 }
 
+// Query is bounded by the statement timeout until the result is closed.
 func (s *sqlSession) Query(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
-	return s.exec.Query(ctx, stmt, params...)
+	ctx, cancel := statement.WithDeadline(ctx, s.timeout)
+	ret, err := s.exec.Query(ctx, stmt, params...)
+	if err != nil {
+		cancel()
+		return nil, statement.CheckTimeout(ctx, err)
+	}
+	return newCancelResultSet(ret, cancel), nil
 }
 
 func (s *sqlSession) Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
-	return s.exec.Execute(ctx, stmt, params...)
+	ctx, cancel := statement.WithDeadline(ctx, s.timeout)
+	defer cancel()
+	ret, err := s.exec.Execute(ctx, stmt, params...)
+	return ret, statement.CheckTimeout(ctx, err)
+}
+
+// ExecuteBatch is bounded by the statement timeout as a whole.
+func (s *sqlSession) ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error) {
+	ctx, cancel := statement.WithDeadline(ctx, s.timeout)
+	defer cancel()
+	ret, err := s.exec.ExecuteBatch(ctx, stmt, params)
+	return ret, statement.CheckTimeout(ctx, err)
 }
 
 func (s *sqlSession) Begin(ctx context.Context) error {
@@ -79,7 +127,7 @@ func (s *sqlSession) Rollback(ctx context.Context) error {
 }
 
 func (s *sqlSession) Close() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.closeTimeout)
 	defer cancel()
 	return s.exec.Close(ctx, false)
 }
@@ -94,3 +142,18 @@ func (o sessOpts) SetExecutorFactory(execFac ExecutorFactory) SessionOpt {
 		session.execFac = execFac
 	}
 }
+
+// SetStatementTimeout sets the default timeout of Query, Execute and ExecuteBatch, 0 means no timeout.
+// It can be overridden by the deadline of ctx or statement.WithTimeout.
+func (o sessOpts) SetStatementTimeout(timeout time.Duration) SessionOpt {
+	return func(session *sqlSession) {
+		session.timeout = timeout
+	}
+}
+
+// SetCloseTimeout sets the timeout of the rollback in Close, default is DefaultCloseTimeout.
+func (o sessOpts) SetCloseTimeout(timeout time.Duration) SessionOpt {
+	return func(session *sqlSession) {
+		session.closeTimeout = timeout
+	}
+}
@@ -20,14 +20,22 @@ import (
 	"context"
 	"database/sql"
 	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/statement"
 )
 
 type sqlStatement sql.Stmt
 
+// transactionStatement is a statement bound to a transaction by sql.Tx.StmtContext.
 type transactionStatement struct {
 	stmt *sql.Stmt
 }
 
+func newTransactionStatement(stmt *sql.Stmt) *transactionStatement {
+	return &transactionStatement{
+		stmt: stmt,
+	}
+}
+
 func (transStatement *transactionStatement) Query(ctx context.Context, params ...interface{}) (resultset.Result, error) {
 	rows, err := transStatement.stmt.QueryContext(ctx, params...)
 	if err != nil {
@@ -44,8 +52,13 @@ func (transStatement *transactionStatement) Execute(ctx context.Context, params
 	return NewSqlExecResultSet(r), nil
 }
 
+func (transStatement *transactionStatement) ExecuteBatch(ctx context.Context, params [][]interface{}) (*resultset.BatchResult, error) {
+	return statement.RunBatch(ctx, params, transStatement.Execute)
+}
+
 func (transStatement *transactionStatement) Close() error {
-	//Will be closed when commit or rollback
+	// Only release the binding of the transaction, the statement of sql.DB which it bound from is still available.
+	// It will be closed automatically when commit or rollback if not be closed.
 	return transStatement.stmt.Close()
 }
 
@@ -67,6 +80,10 @@ func (s *sqlStatement) Execute(ctx context.Context, params ...interface{}) (resu
 	return NewSqlExecResultSet(r), nil
 }
 
+func (s *sqlStatement) ExecuteBatch(ctx context.Context, params [][]interface{}) (*resultset.BatchResult, error) {
+	return statement.RunBatch(ctx, params, s.Execute)
+}
+
 func (s *sqlStatement) Close() error {
 	stmt := (*sql.Stmt)(s)
 	return stmt.Close()
@@ -34,12 +34,35 @@ var (
 	TransactionBeginError      = gobatisError("22004", "Transaction begin error")
 	TransactionHaveBegin       = gobatisError("22005", "Transaction has been begin state")
 	TransactionRollbackError   = gobatisError("22005", "Transaction rollback error")
+	TransactionQueryRejected   = gobatisError("22006", "Query is rejected in buffered transaction")
 	ConnectionPrepareError     = gobatisError("23001", "Connection prepare error")
+	ConnectionNotFound         = gobatisError("23002", "Connection not found")
+	ConnectionClosed           = gobatisError("23003", "Connection has been closed")
+	DriverNotFound             = gobatisError("23004", "Driver not registered")
+	BackendUnavailable         = gobatisError("23005", "Backend unavailable")
 	StatementQueryError        = gobatisError("24001", "statement query error")
 	StatementExecError         = gobatisError("24002", "statement exec error")
+	BatchPartialError          = gobatisError("24003", "some items of batch failed")
+	BatchRewriteError          = gobatisError("24004", "statement cannot be rewritten to multi-values insert")
+	StatementTimeout           = gobatisError("24005", "statement timeout")
+	BatchRolledBack            = gobatisError("24006", "batch item rolled back")
 	QueryTypeError             = gobatisError("25001", "select data convert error")
 	HandlerQueryError          = gobatisError("26001", "Connection prepare error")
 	HandlerExecuteError        = gobatisError("26002", "statement query error")
+	ParamsMalformedError       = gobatisError("27001", "params malformed, expect key-value pairs, named args, a map or a struct")
+	ParamsKeyTypeError         = gobatisError("27002", "params key must be string")
+	ParamsValueTypeError       = gobatisError("27003", "params value type not support")
+	ShardKeyNotFound           = gobatisError("28001", "shard key not found")
+	ShardKeyInvalid            = gobatisError("28002", "shard key invalid")
+	ShardCrossTransaction      = gobatisError("28003", "transaction cannot cross shards")
+	ShardBatchAborted          = gobatisError("28004", "batch items not executed because other shard failed")
+	CircuitOpen                = gobatisError("29001", "circuit breaker is open")
+	ConcurrencyLimitExceeded   = gobatisError("29002", "concurrency limit exceeded")
+	RateLimitExceeded          = gobatisError("29003", "rate limit exceeded")
+	TenantNotFound             = gobatisError("30001", "tenant not found in context")
+	TenantFilterMissing        = gobatisError("30002", "statement lacks tenant filter")
+	TenantRewriteError         = gobatisError("30003", "statement cannot be rewritten with tenant filter")
+	TenantMismatch             = gobatisError("30004", "tenant column does not match the tenant in context")
 	ResultPointerIsNil         = gobatisError("31000", "result type is a nil pointer")
 	ResultIsnotPointer         = gobatisError("31001", "result type is not pointer")
 	ResultPtrValueIsPointer    = gobatisError("31002", "result type is pointer of pointer")
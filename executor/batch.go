@@ -0,0 +1,194 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"github.com/xfali/lean/errors"
+	"github.com/xfali/lean/handler"
+	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/statement"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type batchPrepareFunc func(ctx context.Context, conn handler.Handler, sql string) (statement.Statement, func(), error)
+
+type batchExecuteFunc func(ctx context.Context, conn handler.Handler, sql string, params ...interface{}) (resultset.Result, error)
+
+// executeBatch executes params in a transaction, if the executor has begun a transaction, the batch joins it and
+// the transaction is not committed or rolled back by the batch. If the transaction of batch is rolled back or fails
+// to commit, the succeeded items are marked failed with errors.BatchRolledBack.
+func executeBatch(ctx context.Context, exec Executor, getHandler func() handler.Handler, stmt string, params [][]interface{},
+	prepare batchPrepareFunc, execute batchExecuteFunc) (ret *resultset.BatchResult, err error) {
+	began := true
+	if err := exec.Begin(ctx); err != nil {
+		if err != errors.TransactionHaveBegin {
+			return nil, err
+		}
+		began = false
+	}
+
+	opt := statement.GetBatchOption(ctx)
+	defer func() {
+		if !began {
+			return
+		}
+		if err == nil || (opt.ContinueOnError && err == errors.BatchPartialError) {
+			cerr := exec.Commit(ctx, true)
+			if cerr == nil {
+				return
+			}
+			// the failed transaction is still held by the executor, rolls back to reset it
+			_ = exec.Rollback(ctx, true)
+			err = cerr
+		} else {
+			_ = exec.Rollback(ctx, true)
+		}
+		if ret != nil {
+			ret.Rollback(errors.BatchRolledBack)
+		}
+	}()
+
+	conn := getHandler()
+	if conn == nil {
+		return nil, errors.ExecutorGetConnectionError
+	}
+
+	if opt.Mode == statement.BatchRewrite {
+		return executeRewrite(ctx, conn, stmt, params, opt, execute)
+	}
+
+	st, release, err := prepare(ctx, conn, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return st.ExecuteBatch(ctx, params)
+}
+
+func executeRewrite(ctx context.Context, conn handler.Handler, stmt string, params [][]interface{}, opt statement.BatchOption,
+	execute batchExecuteFunc) (*resultset.BatchResult, error) {
+	ret := resultset.NewBatchResult(len(params))
+	for i := 0; i < len(params); i += opt.ChunkSize {
+		end := i + opt.ChunkSize
+		if end > len(params) {
+			end = len(params)
+		}
+		chunk := params[i:end]
+		sql, err := RewriteInsert(stmt, len(chunk))
+		if err != nil {
+			return ret, err
+		}
+		var values []interface{}
+		for _, p := range chunk {
+			values = append(values, p...)
+		}
+		r, err := execute(ctx, conn, sql, values...)
+		ret.AddChunk(len(chunk), r, err)
+		if err != nil && !opt.ContinueOnError {
+			return ret, err
+		}
+	}
+	if ret.Failed() > 0 {
+		return ret, errors.BatchPartialError
+	}
+	return ret, nil
+}
+
+var (
+	valuesRegexp      = regexp.MustCompile(`(?i)\bVALUES\s*\(`)
+	placeholderRegexp = regexp.MustCompile(`\$(\d+)`)
+)
+
+// RewriteInsert rewrites INSERT ... VALUES (...) statement into INSERT ... VALUES (...), (...) of rows values.
+// Both ? and $n placeholders are supported, $n placeholders are renumbered.
+func RewriteInsert(stmt string, rows int) (string, error) {
+	if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(stmt)), "INSERT") {
+		return "", fmt.Errorf("%w: not an insert statement ", errors.BatchRewriteError)
+	}
+	loc := valuesRegexp.FindStringIndex(stmt)
+	if loc == nil {
+		return "", fmt.Errorf("%w: VALUES not found ", errors.BatchRewriteError)
+	}
+	start := loc[1] - 1
+	end := matchParenthesis(stmt, start)
+	if end < 0 {
+		return "", fmt.Errorf("%w: parenthesis of VALUES not match ", errors.BatchRewriteError)
+	}
+	group := stmt[start : end+1]
+	suffix := stmt[end+1:]
+	if strings.Contains(suffix, "?") || placeholderRegexp.MatchString(suffix) {
+		return "", fmt.Errorf("%w: placeholders after VALUES ", errors.BatchRewriteError)
+	}
+
+	n := 0
+	for _, m := range placeholderRegexp.FindAllStringSubmatch(group, -1) {
+		if i, _ := strconv.Atoi(m[1]); i > n {
+			n = i
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(stmt[:start])
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if n == 0 || i == 0 {
+			sb.WriteString(group)
+			continue
+		}
+		offset := i * n
+		sb.WriteString(placeholderRegexp.ReplaceAllStringFunc(group, func(s string) string {
+			v, _ := strconv.Atoi(s[1:])
+			return "$" + strconv.Itoa(v+offset)
+		}))
+	}
+	sb.WriteString(suffix)
+	return sb.String(), nil
+}
+
+func matchParenthesis(s string, start int) int {
+	depth := 0
+	var quote byte
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -60,6 +60,17 @@ func (d dummyExecutor) Execute(ctx context.Context, stmt string, params ...inter
 	return nil, nil
 }
 
+func (d dummyExecutor) ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error) {
+	if d.sleep > 0 {
+		time.Sleep(d.sleep)
+	}
+	ret := resultset.NewBatchResult(len(params))
+	for range params {
+		ret.Add(nil, nil)
+	}
+	return ret, nil
+}
+
 func (d dummyExecutor) Begin(ctx context.Context) error {
 	if d.sleep > 0 {
 		time.Sleep(d.sleep)
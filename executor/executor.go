@@ -30,6 +30,8 @@ type Executor interface {
 
 	Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error)
 
+	ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error)
+
 	Begin(ctx context.Context) error
 
 	Commit(ctx context.Context, require bool) error
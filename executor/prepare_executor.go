@@ -22,6 +22,7 @@ import (
 	"github.com/xfali/lean/handler"
 	"github.com/xfali/lean/logger"
 	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/statement"
 	"github.com/xfali/lean/transaction"
 	"github.com/xfali/xlog"
 )
@@ -31,7 +32,8 @@ type PrepareExecutor struct {
 	transaction transaction.Transaction
 	closed      bool
 
-	pool handler.StatementPool
+	pool   handler.StatementPool
+	shared bool
 }
 
 func NewPrepareExecutor(transaction transaction.Transaction, pool handler.StatementPool) *PrepareExecutor {
@@ -42,9 +44,26 @@ func NewPrepareExecutor(transaction transaction.Transaction, pool handler.Statem
 	}
 }
 
+// NewSharedPrepareExecutor creates a PrepareExecutor with a pool shared by other executors,
+// only the statements bound to its transaction are purged when it is closed.
+func NewSharedPrepareExecutor(transaction transaction.Transaction, pool handler.SharedStatementPool) *PrepareExecutor {
+	return &PrepareExecutor{
+		logger:      logger.GetLogger(),
+		transaction: transaction,
+		pool:        pool,
+		shared:      true,
+	}
+}
+
 func (exec *PrepareExecutor) Close(ctx context.Context, rollback bool) error {
 	defer func() {
-		exec.pool.PurgeAll()
+		if !exec.shared {
+			exec.pool.PurgeAll()
+		} else if exec.transaction != nil {
+			if h, ok := exec.transaction.GetHandler().(handler.TxHandler); ok {
+				exec.pool.Purge(h)
+			}
+		}
 		if exec.transaction != nil {
 			err := exec.transaction.Close()
 			if err != nil {
@@ -79,15 +98,11 @@ func (exec *PrepareExecutor) Query(ctx context.Context, stmt string, params ...i
 		return nil, errors.ExecutorGetConnectionError
 	}
 
-	statement, have := exec.pool.Get(conn, stmt)
-	if !have {
-		st, err := conn.Prepare(ctx, stmt)
-		if err != nil {
-			return nil, err
-		}
-		statement = st
-		exec.pool.Put(conn, stmt, statement)
+	statement, release, err := exec.prepare(ctx, conn, stmt)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 	return statement.Query(ctx, params...)
 }
 
@@ -101,18 +116,70 @@ func (exec *PrepareExecutor) Execute(ctx context.Context, stmt string, params ..
 		return nil, errors.ExecutorGetConnectionError
 	}
 
-	statement, have := exec.pool.Get(conn, stmt)
-	if !have {
-		st, err := conn.Prepare(ctx, stmt)
+	statement, release, err := exec.prepare(ctx, conn, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return statement.Execute(ctx, params...)
+}
+
+func (exec *PrepareExecutor) ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error) {
+	if exec.closed {
+		return nil, errors.ExecutorQueryError
+	}
+
+	return executeBatch(ctx, exec, exec.transaction.GetHandler, stmt, params, exec.prepare,
+		func(ctx context.Context, conn handler.Handler, sql string, params ...interface{}) (resultset.Result, error) {
+			st, release, err := exec.prepare(ctx, conn, sql)
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+			return st.Execute(ctx, params...)
+		})
+}
+
+// prepare returns the cached statement or prepares a new one, statements of a transaction handler are bound from
+// the statements of its parent. release must be called after the statement is used.
+func (exec *PrepareExecutor) prepare(ctx context.Context, conn handler.Handler, sql string) (statement.Statement, func(), error) {
+	if sp, ok := exec.pool.(handler.SharedStatementPool); ok && exec.shared {
+		if st, release, have := sp.Acquire(conn, sql); have {
+			return st, release, nil
+		}
+		st, err := exec.newStatement(ctx, conn, sql)
+		if err != nil {
+			return nil, nil, err
+		}
+		st, release := sp.PutAndAcquire(conn, sql, st)
+		return st, release, nil
+	}
+
+	if st, have := exec.pool.Get(conn, sql); have {
+		return st, noRelease, nil
+	}
+	st, err := exec.newStatement(ctx, conn, sql)
+	if err != nil {
+		return nil, nil, err
+	}
+	exec.pool.Put(conn, sql, st)
+	return st, noRelease, nil
+}
+
+func (exec *PrepareExecutor) newStatement(ctx context.Context, conn handler.Handler, sql string) (statement.Statement, error) {
+	if txh, ok := conn.(handler.TxHandler); ok && txh.Parent() != nil {
+		parent, release, err := exec.prepare(ctx, txh.Parent(), sql)
 		if err != nil {
 			return nil, err
 		}
-		statement = st
-		exec.pool.Put(conn, stmt, statement)
+		defer release()
+		return txh.Bind(ctx, parent)
 	}
-	return statement.Execute(ctx, params...)
+	return conn.Prepare(ctx, sql)
 }
 
+func noRelease() {}
+
 func (exec *PrepareExecutor) Begin(ctx context.Context) error {
 	if exec.closed {
 		return errors.ExecutorBeginError
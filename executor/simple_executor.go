@@ -19,8 +19,10 @@ package executor
 import (
 	"context"
 	"github.com/xfali/lean/errors"
+	"github.com/xfali/lean/handler"
 	"github.com/xfali/lean/logger"
 	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/statement"
 	"github.com/xfali/lean/transaction"
 	"github.com/xfali/xlog"
 )
@@ -90,6 +92,24 @@ func (exec *SimpleExecutor) Execute(ctx context.Context, stmt string, params ...
 	return conn.Execute(ctx, stmt, params...)
 }
 
+func (exec *SimpleExecutor) ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error) {
+	if exec.closed {
+		return nil, errors.ExecutorQueryError
+	}
+
+	return executeBatch(ctx, exec, exec.transaction.GetHandler, stmt, params,
+		func(ctx context.Context, conn handler.Handler, sql string) (statement.Statement, func(), error) {
+			st, err := conn.Prepare(ctx, sql)
+			if err != nil {
+				return nil, nil, err
+			}
+			return st, func() { _ = st.Close() }, nil
+		},
+		func(ctx context.Context, conn handler.Handler, sql string, params ...interface{}) (resultset.Result, error) {
+			return conn.Execute(ctx, sql, params...)
+		})
+}
+
 func (exec *SimpleExecutor) Begin(ctx context.Context) error {
 	if exec.closed {
 		return errors.ExecutorBeginError
@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package extensions
+
+import (
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/executor"
+	"github.com/xfali/lean/session"
+	"reflect"
+	"sync"
+)
+
+type advisor struct {
+	cut    aop.PointCut
+	advice aop.Advice
+}
+
+type adviceKey struct {
+	t      reflect.Type
+	method string
+}
+
+// advisors are shared by the extensions of connection and the sessions, transactions, handlers and statements
+// it produces, so that one advice covers the whole stack.
+type advisors struct {
+	locker sync.RWMutex
+	list   []advisor
+	cache  map[adviceKey][]aop.Advice
+}
+
+func newAdvisors() *advisors {
+	return &advisors{
+		cache: map[adviceKey][]aop.Advice{},
+	}
+}
+
+func (a *advisors) add(cut aop.PointCut, advice aop.Advice) {
+	a.locker.Lock()
+	defer a.locker.Unlock()
+
+	a.list = append(a.list, advisor{cut: cut, advice: advice})
+	a.cache = map[adviceKey][]aop.Advice{}
+}
+
+func (a *advisors) all() []advisor {
+	a.locker.RLock()
+	defer a.locker.RUnlock()
+
+	return append([]advisor(nil), a.list...)
+}
+
+// match returns the advices of method of target, the first added is the outermost.
+func (a *advisors) match(target interface{}, method string) []aop.Advice {
+	key := adviceKey{t: reflect.TypeOf(target), method: method}
+	a.locker.RLock()
+	ret, ok := a.cache[key]
+	a.locker.RUnlock()
+	if ok {
+		return ret
+	}
+
+	a.locker.Lock()
+	defer a.locker.Unlock()
+	if m, ok := key.t.MethodByName(method); ok {
+		for _, v := range a.list {
+			if v.cut.Matches(m, key.t) {
+				ret = append(ret, v.advice)
+			}
+		}
+	}
+	a.cache[key] = ret
+	return ret
+}
+
+// call invokes method of target through the matched advices, invoke calls target with the params of the innermost advice.
+func (a *advisors) call(target interface{}, method string, params []interface{}, invoke func(params []interface{}) []interface{}) []interface{} {
+	advices := a.match(target, method)
+	if len(advices) == 0 {
+		return invoke(params)
+	}
+	var inv aop.Invocation = &funcInvocation{target: target, name: method, invoke: invoke}
+	for i := len(advices) - 1; i > 0; i-- {
+		advice, next := advices[i], inv
+		inv = &funcInvocation{target: target, name: method, invoke: func(params []interface{}) []interface{} {
+			return advice(next, params)
+		}}
+	}
+	return advices[0](inv, params)
+}
+
+var (
+	sessionType  = reflect.TypeOf((*session.Session)(nil)).Elem()
+	executorType = reflect.TypeOf((*executor.Executor)(nil)).Elem()
+)
+
+// sessionPointCut matches the methods of session.Session and executor.Executor only.
+type sessionPointCut struct {
+	cut aop.PointCut
+}
+
+func (p sessionPointCut) Matches(method reflect.Method, instanceType reflect.Type, params ...interface{}) bool {
+	if !instanceType.Implements(sessionType) && !instanceType.Implements(executorType) {
+		return false
+	}
+	return p.cut.Matches(method, instanceType, params...)
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package audit
+
+import (
+	"context"
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/extensions"
+	"time"
+)
+
+// Principal is who runs the statements.
+type Principal struct {
+	ID    string            `json:"id,omitempty"`
+	Name  string            `json:"name,omitempty"`
+	Attrs map[string]string `json:"attrs,omitempty"`
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns ctx carrying principal, statements executed with it are audited as the principal.
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the principal set by WithPrincipal.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Record is the audit record of a statement.
+type Record struct {
+	Time         time.Time       `json:"time"`
+	Principal    Principal       `json:"principal"`
+	DataSource   string          `json:"datasource,omitempty"`
+	Method       string          `json:"method"`
+	Operation    string          `json:"operation"`
+	Statement    string          `json:"statement"`
+	Params       []interface{}   `json:"params,omitempty"`
+	BatchParams  [][]interface{} `json:"batchParams,omitempty"`
+	RowsAffected int64           `json:"rowsAffected"`
+	Duration     time.Duration   `json:"duration"`
+	Error        string          `json:"error,omitempty"`
+}
+
+type Opt func(*advice)
+
+type advice struct {
+	auditor    *Auditor
+	query      bool
+	params     bool
+	datasource string
+	principal  func(ctx context.Context) Principal
+	redact     extensions.RedactFunc
+}
+
+// NewAdvice creates advice which records Execute and ExecuteBatch, and Query if SetQueryEnabled, to auditor.
+// Failed statements are recorded with the error.
+func NewAdvice(auditor *Auditor, opts ...Opt) aop.Advice {
+	a := &advice{
+		auditor: auditor,
+		params:  true,
+		principal: func(ctx context.Context) Principal {
+			p, _ := PrincipalFrom(ctx)
+			return p
+		},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a.advice
+}
+
+// WithAudit extends the Connection / Session / Executor with audit advice.
+func WithAudit(ext extensions.Extension, auditor *Auditor, opts ...Opt) extensions.Extension {
+	return ext.Extend(extensions.PointCutStatement(), NewAdvice(auditor, opts...))
+}
+
+func (a *advice) advice(invocation aop.Invocation, params []interface{}) []interface{} {
+	call := extensions.ParseCall(invocation.MethodName(), params)
+	if call.Method == extensions.MethodQuery && !a.query {
+		return invocation.Invoke(params)
+	}
+	now := time.Now()
+	ret := invocation.Invoke(params)
+	outcome := extensions.ParseOutcome(ret)
+
+	r := Record{
+		Time:         now,
+		Principal:    a.principal(call.Ctx),
+		DataSource:   a.datasource,
+		Method:       call.Method,
+		Operation:    call.Operation(),
+		Statement:    call.Stmt,
+		RowsAffected: outcome.RowsAffected(call.Method),
+		Duration:     time.Since(now),
+	}
+	if a.params {
+		r.Params = a.redactParams(call.Stmt, call.Params)
+		for _, v := range call.BatchParams {
+			r.BatchParams = append(r.BatchParams, a.redactParams(call.Stmt, v))
+		}
+	}
+	if outcome.Err != nil {
+		r.Error = outcome.Err.Error()
+	}
+	a.auditor.Record(r)
+	return ret
+}
+
+func (a *advice) redactParams(stmt string, params []interface{}) []interface{} {
+	if len(params) == 0 {
+		return nil
+	}
+	ret := make([]interface{}, len(params))
+	if a.redact == nil {
+		copy(ret, params)
+		return ret
+	}
+	columns := extensions.ParamColumns(stmt, params)
+	for i, v := range params {
+		ret[i] = a.redact(stmt, i, columns[i], v)
+	}
+	return ret
+}
+
+type opts struct{}
+
+var Opts opts
+
+// SetQueryEnabled records Query too, default is false.
+func (opts) SetQueryEnabled(enable bool) Opt {
+	return func(a *advice) {
+		a.query = enable
+	}
+}
+
+// SetParamsEnabled records params of statements, default is true.
+func (opts) SetParamsEnabled(enable bool) Opt {
+	return func(a *advice) {
+		a.params = enable
+	}
+}
+
+// SetDataSource sets the datasource name of records.
+func (opts) SetDataSource(name string) Opt {
+	return func(a *advice) {
+		a.datasource = name
+	}
+}
+
+// SetPrincipalFunc sets the function returning principal from ctx, default is PrincipalFrom.
+func (opts) SetPrincipalFunc(f func(ctx context.Context) Principal) Opt {
+	return func(a *advice) {
+		a.principal = f
+	}
+}
+
+// SetRedactColumns replaces the params of columns with extensions.RedactedValue, see extensions.RedactColumns.
+func (opts) SetRedactColumns(columns ...string) Opt {
+	return func(a *advice) {
+		a.redact = extensions.RedactColumns(columns...)
+	}
+}
+
+// SetRedactFunc sets the function to redact params, it overrides SetRedactColumns.
+func (opts) SetRedactFunc(f extensions.RedactFunc) Opt {
+	return func(a *advice) {
+		a.redact = f
+	}
+}
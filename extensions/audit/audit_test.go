@@ -0,0 +1,176 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/xfali/lean/extensions"
+	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/session"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memorySink struct {
+	locker  sync.Mutex
+	records []Record
+	block   chan struct{}
+	closed  bool
+}
+
+func (s *memorySink) Write(ctx context.Context, records []Record) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestAudit(t *testing.T) {
+	sink := &memorySink{}
+	auditor := NewAuditor(sink, AuditorOpts.SetBatchSize(2), AuditorOpts.SetFlushInterval(10*time.Millisecond))
+	sess := extensions.NewSessionEx(session.NewDummySession(0))
+	WithAudit(sess, auditor, Opts.SetDataSource("users"), Opts.SetRedactColumns("password"))
+
+	ctx := WithPrincipal(context.Background(), Principal{ID: "1", Name: "tom"})
+	sess.Execute(ctx, "update users set password = ? where id = ?", "secret", 1)
+	sess.Query(ctx, "select * from users where id = ?", 1)
+	sess.ExecuteBatch(context.Background(), "insert into users (name, password) values (?, ?)",
+		[][]interface{}{{"a", "x"}, {"b", "y"}})
+	if err := auditor.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.records) != 2 || !sink.closed {
+		t.Fatal("expect 2 records and sink closed but get ", sink.records)
+	}
+	r := sink.records[0]
+	if r.Principal.Name != "tom" || r.DataSource != "users" || r.Operation != "UPDATE" ||
+		r.Params[0] != extensions.RedactedValue || r.Params[1] != 1 {
+		t.Fatal(r)
+	}
+	r = sink.records[1]
+	if r.Principal.ID != "" || r.Method != extensions.MethodExecuteBatch || len(r.BatchParams) != 2 ||
+		r.BatchParams[1][0] != "b" || r.BatchParams[1][1] != extensions.RedactedValue {
+		t.Fatal(r)
+	}
+	if auditor.Record(Record{}) || auditor.Dropped() != 1 {
+		t.Fatal("expect record dropped after close")
+	}
+}
+
+func TestAuditorNonBlocking(t *testing.T) {
+	sink := &memorySink{block: make(chan struct{})}
+	auditor := NewAuditor(sink, AuditorOpts.SetBufferSize(1), AuditorOpts.SetBatchSize(1))
+	begin := time.Now()
+	for i := 0; i < 10; i++ {
+		auditor.Record(Record{Statement: "update"})
+	}
+	if time.Since(begin) > 100*time.Millisecond {
+		t.Fatal("expect Record not blocked")
+	}
+	// one is being written, one is in buffer
+	if auditor.Dropped() < 8 {
+		t.Fatal("expect records dropped but get ", auditor.Dropped())
+	}
+	close(sink.block)
+	if err := auditor.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(sink.records))+auditor.Dropped() != 10 {
+		t.Fatal(len(sink.records), auditor.Dropped())
+	}
+}
+
+func TestJSONLSink(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sink := NewJSONLSink(buf)
+	err := sink.Write(context.Background(), []Record{
+		{Statement: "delete from tbl where id = ?", Params: []interface{}{1}},
+		{Statement: "update tbl set name = ?", Error: "failed"},
+		{Statement: "update tbl set score = ? where id = ?", Params: []interface{}{math.NaN(), 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(buf)
+	var records []Record
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 3 || records[1].Error != "failed" || records[0].Params[0] != float64(1) {
+		t.Fatal(records)
+	}
+	if records[2].Params[0] != "NaN" || records[2].Params[1] != float64(2) {
+		t.Fatal("expect NaN printed and other params kept ", records[2].Params)
+	}
+}
+
+type testConn struct {
+	sess *testSession
+}
+
+func (c *testConn) Open() error { return nil }
+
+func (c *testConn) GetSession() (session.Session, error) { return c.sess, nil }
+
+func (c *testConn) Close() error { return nil }
+
+type testSession struct {
+	session.Session
+	stmt string
+	rows [][]interface{}
+}
+
+func (s *testSession) ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error) {
+	s.stmt, s.rows = stmt, params
+	return nil, nil
+}
+
+func TestTableSink(t *testing.T) {
+	sess := &testSession{Session: session.NewDummySession(0)}
+	sink := NewTableSink(&testConn{sess: sess}, "audit_log", TableOpts.SetPlaceholder(DollarPlaceholder))
+	err := sink.Write(context.Background(), []Record{
+		{Principal: Principal{ID: "1"}, Statement: "delete from tbl where id = ?", Params: []interface{}{1}, Duration: time.Second},
+		{Statement: "insert into tbl (score) values (?)", BatchParams: [][]interface{}{{1.5}, {math.Inf(1)}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sess.stmt, "INSERT INTO audit_log (occurred_at, principal_id") ||
+		!strings.HasSuffix(sess.stmt, "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)") {
+		t.Fatal(sess.stmt)
+	}
+	if len(sess.rows) != 2 || sess.rows[1][7] != `[[1.5],["+Inf"]]` || sess.rows[0][1] != "1" || sess.rows[0][7] != "[1]" || sess.rows[0][9] != int64(1000) {
+		t.Fatal(sess.rows)
+	}
+}
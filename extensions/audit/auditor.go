@@ -0,0 +1,196 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package audit
+
+import (
+	"context"
+	"github.com/xfali/lean/logger"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultBufferSize    = 4096
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = time.Second
+	DefaultWriteTimeout  = 10 * time.Second
+)
+
+// Sink writes audit records, Write is called by one goroutine of Auditor and records must not be retained
+// after it returns.
+type Sink interface {
+	Write(ctx context.Context, records []Record) error
+	Close() error
+}
+
+type AuditorOpt func(*Auditor)
+
+// Auditor buffers records and writes them to sink in batches by a background goroutine. Record never blocks,
+// records are dropped if the buffer is full.
+type Auditor struct {
+	sink          Sink
+	batchSize     int
+	flushInterval time.Duration
+	writeTimeout  time.Duration
+	onError       func(err error, records []Record)
+
+	records chan Record
+	dropped int64
+	closed  int32
+
+	stopC     chan struct{}
+	doneC     chan struct{}
+	closeOnce sync.Once
+}
+
+func NewAuditor(sink Sink, opts ...AuditorOpt) *Auditor {
+	ret := &Auditor{
+		sink:          sink,
+		batchSize:     DefaultBatchSize,
+		flushInterval: DefaultFlushInterval,
+		writeTimeout:  DefaultWriteTimeout,
+		records:       make(chan Record, DefaultBufferSize),
+		stopC:         make(chan struct{}),
+		doneC:         make(chan struct{}),
+		onError: func(err error, records []Record) {
+			logger.GetLogger().Errorln("Write", len(records), "audit records failed:", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	go ret.loop()
+	return ret
+}
+
+// Record adds r to the buffer, it returns false if the buffer is full or the auditor is closed.
+func (a *Auditor) Record(r Record) bool {
+	if atomic.LoadInt32(&a.closed) == 1 {
+		atomic.AddInt64(&a.dropped, 1)
+		return false
+	}
+	select {
+	case a.records <- r:
+		return true
+	default:
+		atomic.AddInt64(&a.dropped, 1)
+		return false
+	}
+}
+
+// Dropped returns the number of records dropped.
+func (a *Auditor) Dropped() int64 {
+	return atomic.LoadInt64(&a.dropped)
+}
+
+// Close writes the buffered records and closes the sink, it gives up waiting if ctx is done.
+func (a *Auditor) Close(ctx context.Context) error {
+	a.closeOnce.Do(func() {
+		atomic.StoreInt32(&a.closed, 1)
+		close(a.stopC)
+	})
+	select {
+	case <-a.doneC:
+		return a.sink.Close()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *Auditor) loop() {
+	defer close(a.doneC)
+
+	ticker := time.NewTicker(a.flushInterval)
+	defer ticker.Stop()
+	batch := make([]Record, 0, a.batchSize)
+	for {
+		select {
+		case r := <-a.records:
+			batch = append(batch, r)
+			if len(batch) >= a.batchSize {
+				batch = a.write(batch)
+			}
+		case <-ticker.C:
+			batch = a.write(batch)
+		case <-a.stopC:
+			for {
+				select {
+				case r := <-a.records:
+					batch = append(batch, r)
+					if len(batch) >= a.batchSize {
+						batch = a.write(batch)
+					}
+				default:
+					a.write(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// write writes batch and returns an empty batch reusing its memory.
+func (a *Auditor) write(batch []Record) []Record {
+	if len(batch) == 0 {
+		return batch
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), a.writeTimeout)
+	defer cancel()
+	if err := a.sink.Write(ctx, batch); err != nil && a.onError != nil {
+		a.onError(err, batch)
+	}
+	return batch[:0]
+}
+
+type auditorOpts struct{}
+
+var AuditorOpts auditorOpts
+
+// SetBufferSize sets the capacity of buffer, default is DefaultBufferSize.
+func (auditorOpts) SetBufferSize(size int) AuditorOpt {
+	return func(a *Auditor) {
+		a.records = make(chan Record, size)
+	}
+}
+
+// SetBatchSize sets the max records of one write, default is DefaultBatchSize.
+func (auditorOpts) SetBatchSize(size int) AuditorOpt {
+	return func(a *Auditor) {
+		a.batchSize = size
+	}
+}
+
+// SetFlushInterval sets the interval to write records less than batch size, default is DefaultFlushInterval.
+func (auditorOpts) SetFlushInterval(interval time.Duration) AuditorOpt {
+	return func(a *Auditor) {
+		a.flushInterval = interval
+	}
+}
+
+// SetWriteTimeout sets the timeout of Sink.Write, default is DefaultWriteTimeout.
+func (auditorOpts) SetWriteTimeout(timeout time.Duration) AuditorOpt {
+	return func(a *Auditor) {
+		a.writeTimeout = timeout
+	}
+}
+
+// SetErrorHandler sets the function called with the records failed to write, default logs the error.
+func (auditorOpts) SetErrorHandler(f func(err error, records []Record)) AuditorOpt {
+	return func(a *Auditor) {
+		a.onError = f
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/xfali/lean/connection"
+	"io"
+	"os"
+	"strings"
+)
+
+// JSONLSink writes records as JSON lines.
+type JSONLSink struct {
+	w io.Writer
+}
+
+// NewJSONLSink creates sink writing to w, w is closed by Close if it implements io.Closer and synced after every
+// write if it has Sync() error, e.g. *os.File.
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{w: w}
+}
+
+// NewFileSink creates JSONLSink appending to file of path.
+func NewFileSink(path string) (*JSONLSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Open audit file failed: %v ", err)
+	}
+	return NewJSONLSink(f), nil
+}
+
+func (s *JSONLSink) Write(ctx context.Context, records []Record) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range records {
+		// Encode writes nothing if it fails
+		if err := enc.Encode(&records[i]); err != nil {
+			r := printable(records[i])
+			if err := enc.Encode(&r); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := s.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if f, ok := s.w.(interface{ Sync() error }); ok {
+		return f.Sync()
+	}
+	return nil
+}
+
+func (s *JSONLSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// TableColumns are the columns of audit table written by TableSink in order.
+var TableColumns = []string{
+	"occurred_at", "principal_id", "principal_name", "datasource", "method", "operation",
+	"statement", "params", "rows_affected", "duration_ms", "error",
+}
+
+type TableOpt func(*TableSink)
+
+// TableSink inserts records into a database table with TableColumns, params are stored as JSON.
+// The connection should not be extended by audit advice, otherwise the inserts are audited too.
+type TableSink struct {
+	conn        connection.Connection
+	stmt        string
+	placeholder func(index int) string
+}
+
+// NewTableSink creates sink inserting records into table by ExecuteBatch of sessions of conn.
+func NewTableSink(conn connection.Connection, table string, opts ...TableOpt) *TableSink {
+	ret := &TableSink{
+		conn:        conn,
+		placeholder: QuestionPlaceholder,
+	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	marks := make([]string, len(TableColumns))
+	for i := range marks {
+		marks[i] = ret.placeholder(i + 1)
+	}
+	ret.stmt = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table,
+		strings.Join(TableColumns, ", "), strings.Join(marks, ", "))
+	return ret
+}
+
+// QuestionPlaceholder returns ?, e.g. for mysql and sqlite.
+func QuestionPlaceholder(index int) string {
+	return "?"
+}
+
+// DollarPlaceholder returns $index, e.g. for postgres.
+func DollarPlaceholder(index int) string {
+	return fmt.Sprintf("$%d", index)
+}
+
+func (s *TableSink) Write(ctx context.Context, records []Record) error {
+	rows := make([][]interface{}, len(records))
+	for i, r := range records {
+		var params interface{} = r.Params
+		if r.BatchParams != nil {
+			params = r.BatchParams
+		}
+		data, err := json.Marshal(params)
+		if err != nil {
+			r = printable(r)
+			params = r.Params
+			if r.BatchParams != nil {
+				params = r.BatchParams
+			}
+			if data, err = json.Marshal(params); err != nil {
+				return err
+			}
+		}
+		rows[i] = []interface{}{
+			r.Time, r.Principal.ID, r.Principal.Name, r.DataSource, r.Method, r.Operation,
+			r.Statement, string(data), r.RowsAffected, r.Duration.Milliseconds(), r.Error,
+		}
+	}
+	sess, err := s.conn.GetSession()
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+	_, err = sess.ExecuteBatch(ctx, s.stmt, rows)
+	return err
+}
+
+// Close does not close the connection.
+func (s *TableSink) Close() error {
+	return nil
+}
+
+// printable returns the record whose params which can not be encoded to JSON, e.g. NaN and channels,
+// are replaced by fmt.Sprint of them.
+func printable(r Record) Record {
+	r.Params = printableParams(r.Params)
+	if r.BatchParams != nil {
+		batch := make([][]interface{}, len(r.BatchParams))
+		for i, v := range r.BatchParams {
+			batch[i] = printableParams(v)
+		}
+		r.BatchParams = batch
+	}
+	return r
+}
+
+func printableParams(params []interface{}) []interface{} {
+	if params == nil {
+		return nil
+	}
+	ret := make([]interface{}, len(params))
+	for i, v := range params {
+		if _, err := json.Marshal(v); err != nil {
+			ret[i] = fmt.Sprint(v)
+		} else {
+			ret[i] = v
+		}
+	}
+	return ret
+}
+
+type tableOpts struct{}
+
+var TableOpts tableOpts
+
+// SetPlaceholder sets the placeholder of insert statement by 1-based index, default is QuestionPlaceholder.
+func (tableOpts) SetPlaceholder(f func(index int) string) TableOpt {
+	return func(s *TableSink) {
+		s.placeholder = f
+	}
+}
@@ -21,6 +21,9 @@ import (
 	"strings"
 )
 
+// Caller returns the name of the calling function, e.g. the method name passed to proxy.Call.
+//
+// Deprecated: extensions of this package use the Method constants, e.g. MethodQuery, instead.
 func Caller() string {
 	pc := make([]uintptr, 1)
 	runtime.Callers(2, pc)
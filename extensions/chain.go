@@ -0,0 +1,265 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package extensions
+
+import (
+	"context"
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/resultset"
+	"reflect"
+)
+
+type PingFunc func(ctx context.Context) bool
+
+type QueryFunc func(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error)
+
+type ExecuteFunc func(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error)
+
+type ExecuteBatchFunc func(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error)
+
+type BeginFunc func(ctx context.Context) error
+
+// EndFunc is the function of Commit and Rollback, require is always true for session.
+type EndFunc func(ctx context.Context, require bool) error
+
+// CloseFunc is the function of Close, ctx is context.Background() and rollback is false for session.
+type CloseFunc func(ctx context.Context, rollback bool) error
+
+// Interceptor wraps the methods of Session / Executor, nil fields are skipped.
+type Interceptor struct {
+	Ping         func(next PingFunc) PingFunc
+	Query        func(next QueryFunc) QueryFunc
+	Execute      func(next ExecuteFunc) ExecuteFunc
+	ExecuteBatch func(next ExecuteBatchFunc) ExecuteBatchFunc
+	Begin        func(next BeginFunc) BeginFunc
+	Commit       func(next EndFunc) EndFunc
+	Rollback     func(next EndFunc) EndFunc
+	Close        func(next CloseFunc) CloseFunc
+}
+
+type chainFuncs struct {
+	ping         PingFunc
+	query        QueryFunc
+	execute      ExecuteFunc
+	executeBatch ExecuteBatchFunc
+	begin        BeginFunc
+	commit       EndFunc
+	rollback     EndFunc
+	close        CloseFunc
+}
+
+// wrap applies interceptors to base, the first interceptor is the outermost one.
+func (f chainFuncs) wrap(interceptors []Interceptor) chainFuncs {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		v := interceptors[i]
+		if v.Ping != nil {
+			f.ping = v.Ping(f.ping)
+		}
+		if v.Query != nil {
+			f.query = v.Query(f.query)
+		}
+		if v.Execute != nil {
+			f.execute = v.Execute(f.execute)
+		}
+		if v.ExecuteBatch != nil {
+			f.executeBatch = v.ExecuteBatch(f.executeBatch)
+		}
+		if v.Begin != nil {
+			f.begin = v.Begin(f.begin)
+		}
+		if v.Commit != nil {
+			f.commit = v.Commit(f.commit)
+		}
+		if v.Rollback != nil {
+			f.rollback = v.Rollback(f.rollback)
+		}
+		if v.Close != nil {
+			f.close = v.Close(f.close)
+		}
+	}
+	return f
+}
+
+type funcInvocation struct {
+	target interface{}
+	name   string
+	invoke func(params []interface{}) []interface{}
+}
+
+func (i *funcInvocation) Invoke(params []interface{}) []interface{} {
+	return i.invoke(params)
+}
+
+func (i *funcInvocation) MethodName() string {
+	return i.name
+}
+
+// Target returns the extended session, executor, transaction, handler or statement.
+func (i *funcInvocation) Target() interface{} {
+	return i.target
+}
+
+// adviceInterceptor adapts aop advice to Interceptor. Pointcut is matched against the methods of target once,
+// params passed to advice are the arguments of session.Session (session is true) or executor.Executor method,
+// variadic params are flattened so that advice can rewrite them.
+func adviceInterceptor(target interface{}, cut aop.PointCut, advice aop.Advice, session bool) Interceptor {
+	t := reflect.TypeOf(target)
+	match := func(name string) bool {
+		m, ok := t.MethodByName(name)
+		return ok && cut.Matches(m, t)
+	}
+	var ret Interceptor
+	if match(MethodPing) {
+		ret.Ping = func(next PingFunc) PingFunc {
+			inv := &funcInvocation{target: target, name: MethodPing, invoke: func(p []interface{}) []interface{} {
+				return []interface{}{next(ctxAt(p, 0))}
+			}}
+			return func(ctx context.Context) bool {
+				r := advice(inv, []interface{}{ctx})
+				v, _ := valueAt(r, 0).(bool)
+				return v
+			}
+		}
+	}
+	if match(MethodQuery) {
+		ret.Query = func(next QueryFunc) QueryFunc {
+			inv := &funcInvocation{target: target, name: MethodQuery, invoke: func(p []interface{}) []interface{} {
+				r, err := next(ctxAt(p, 0), stringAt(p, 1), variadicAt(p, 2)...)
+				return []interface{}{r, err}
+			}}
+			return func(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+				r := advice(inv, append([]interface{}{ctx, stmt}, params...))
+				return resultAt(r, 0), errorAt(r, 1)
+			}
+		}
+	}
+	if match(MethodExecute) {
+		ret.Execute = func(next ExecuteFunc) ExecuteFunc {
+			inv := &funcInvocation{target: target, name: MethodExecute, invoke: func(p []interface{}) []interface{} {
+				r, err := next(ctxAt(p, 0), stringAt(p, 1), variadicAt(p, 2)...)
+				return []interface{}{r, err}
+			}}
+			return func(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+				r := advice(inv, append([]interface{}{ctx, stmt}, params...))
+				return resultAt(r, 0), errorAt(r, 1)
+			}
+		}
+	}
+	if match(MethodExecuteBatch) {
+		ret.ExecuteBatch = func(next ExecuteBatchFunc) ExecuteBatchFunc {
+			inv := &funcInvocation{target: target, name: MethodExecuteBatch, invoke: func(p []interface{}) []interface{} {
+				batch, _ := valueAt(p, 2).([][]interface{})
+				r, err := next(ctxAt(p, 0), stringAt(p, 1), batch)
+				return []interface{}{r, err}
+			}}
+			return func(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error) {
+				r := advice(inv, []interface{}{ctx, stmt, params})
+				v, _ := valueAt(r, 0).(*resultset.BatchResult)
+				return v, errorAt(r, 1)
+			}
+		}
+	}
+	if match(MethodBegin) {
+		ret.Begin = func(next BeginFunc) BeginFunc {
+			inv := &funcInvocation{target: target, name: MethodBegin, invoke: func(p []interface{}) []interface{} {
+				return []interface{}{next(ctxAt(p, 0))}
+			}}
+			return func(ctx context.Context) error {
+				return errorAt(advice(inv, []interface{}{ctx}), 0)
+			}
+		}
+	}
+	end := func(name string) func(next EndFunc) EndFunc {
+		return func(next EndFunc) EndFunc {
+			inv := &funcInvocation{target: target, name: name, invoke: func(p []interface{}) []interface{} {
+				require := true
+				if !session {
+					require, _ = valueAt(p, 1).(bool)
+				}
+				return []interface{}{next(ctxAt(p, 0), require)}
+			}}
+			return func(ctx context.Context, require bool) error {
+				params := []interface{}{ctx}
+				if !session {
+					params = append(params, require)
+				}
+				return errorAt(advice(inv, params), 0)
+			}
+		}
+	}
+	if match(MethodCommit) {
+		ret.Commit = end(MethodCommit)
+	}
+	if match(MethodRollback) {
+		ret.Rollback = end(MethodRollback)
+	}
+	if match(MethodClose) {
+		ret.Close = func(next CloseFunc) CloseFunc {
+			inv := &funcInvocation{target: target, name: MethodClose, invoke: func(p []interface{}) []interface{} {
+				if session {
+					return []interface{}{next(context.Background(), false)}
+				}
+				rollback, _ := valueAt(p, 1).(bool)
+				return []interface{}{next(ctxAt(p, 0), rollback)}
+			}}
+			return func(ctx context.Context, rollback bool) error {
+				var params []interface{}
+				if !session {
+					params = []interface{}{ctx, rollback}
+				}
+				return errorAt(advice(inv, params), 0)
+			}
+		}
+	}
+	return ret
+}
+
+func valueAt(v []interface{}, i int) interface{} {
+	if i < len(v) {
+		return v[i]
+	}
+	return nil
+}
+
+func ctxAt(v []interface{}, i int) context.Context {
+	if ctx, ok := valueAt(v, i).(context.Context); ok && ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+func stringAt(v []interface{}, i int) string {
+	s, _ := valueAt(v, i).(string)
+	return s
+}
+
+func variadicAt(v []interface{}, i int) []interface{} {
+	if i < len(v) {
+		return v[i:]
+	}
+	return nil
+}
+
+func resultAt(v []interface{}, i int) resultset.Result {
+	r, _ := valueAt(v, i).(resultset.Result)
+	return r
+}
+
+func errorAt(v []interface{}, i int) error {
+	e, _ := valueAt(v, i).(error)
+	return e
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package extensions
+
+import (
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/connection"
+	"github.com/xfali/lean/session"
+	"github.com/xfali/lean/transaction"
+)
+
+// ConnectionEx extends connection.Connection by aop advice. Sessions got from it are extended by the same advice,
+// if the connection implements connection.TransactionWrapping, the transactions, handlers and statements used by
+// the sessions are extended too. Advice should be added before GetSession.
+type ConnectionEx struct {
+	conn     connection.Connection
+	advisors *advisors
+}
+
+func NewConnectionEx(conn connection.Connection) *ConnectionEx {
+	return &ConnectionEx{
+		conn:     conn,
+		advisors: newAdvisors(),
+	}
+}
+
+func (c *ConnectionEx) Extend(cut aop.PointCut, advice aop.Advice) Extension {
+	c.advisors.add(cut, advice)
+	return c
+}
+
+func (c *ConnectionEx) Open() error {
+	r := c.advisors.call(c.conn, MethodOpen, nil, func(p []interface{}) []interface{} {
+		return []interface{}{c.conn.Open()}
+	})
+	return errorAt(r, 0)
+}
+
+func (c *ConnectionEx) GetSession() (session.Session, error) {
+	r := c.advisors.call(c.conn, MethodGetSession, nil, func(p []interface{}) []interface{} {
+		var (
+			sess session.Session
+			err  error
+		)
+		if w, ok := c.conn.(connection.TransactionWrapping); ok {
+			sess, err = w.GetWrappedSession(func(tx transaction.Transaction) transaction.Transaction {
+				return newTransactionEx(tx, c.advisors)
+			})
+		} else {
+			sess, err = c.conn.GetSession()
+		}
+		return []interface{}{sess, err}
+	})
+	sess, _ := valueAt(r, 0).(session.Session)
+	if sess == nil {
+		return nil, errorAt(r, 1)
+	}
+	ret := NewSessionEx(sess)
+	for _, v := range c.advisors.all() {
+		ret.Extend(v.cut, v.advice)
+	}
+	return ret, errorAt(r, 1)
+}
+
+func (c *ConnectionEx) Close() error {
+	r := c.advisors.call(c.conn, MethodClose, nil, func(p []interface{}) []interface{} {
+		return []interface{}{c.conn.Close()}
+	})
+	return errorAt(r, 0)
+}
+
+// PoolStats returns the statistics of the extended connection, zero if it does not implement
+// connection.StatsProvider.
+func (c *ConnectionEx) PoolStats() connection.PoolStats {
+	ret, _ := connection.GetPoolStats(c.conn)
+	return ret
+}
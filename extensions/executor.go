@@ -17,109 +17,25 @@
 package extensions
 
 import (
-	"context"
 	"github.com/xfali/aop"
 	"github.com/xfali/lean/executor"
-	"github.com/xfali/lean/resultset"
 )
 
+// ExecutorEx extends executor.Executor by aop advice.
+// Advice receives the arguments of executor method with variadic params flattened,
+// and params passed to Invoke are forwarded to the executor as they are.
 type ExecutorEx struct {
-	exec  executor.Executor
-	proxy aop.Proxy
+	*ExecutorChain
 }
 
 func NewExecutorEx(exec executor.Executor) *ExecutorEx {
 	ret := &ExecutorEx{
-		exec:  exec,
-		proxy: aop.New(exec),
+		ExecutorChain: NewExecutorChain(exec),
 	}
 	return ret
 }
 
 func (s *ExecutorEx) Extend(cut aop.PointCut, advice aop.Advice) Extension {
-	s.proxy.AddAdvisor(cut, advice)
+	s.ExecutorChain.Extend(cut, advice)
 	return s
 }
-
-func (s *ExecutorEx) Ping(ctx context.Context) (ret bool) {
-	r, err := s.proxy.Call(Caller(), ctx)
-	if err != nil {
-		return false
-	}
-	if r[0] != nil {
-		ret = r[0].(bool)
-	}
-	return
-}
-
-func (s *ExecutorEx) Query(ctx context.Context, stmt string, params ...interface{}) (ret resultset.Result, e error) {
-	r, err := s.proxy.Call(Caller(), ctx, stmt, params)
-	if err != nil {
-		return nil, err
-	}
-	if r[0] != nil {
-		ret = r[0].(resultset.Result)
-	}
-	if r[1] != nil {
-		e = r[1].(error)
-	}
-	return
-}
-
-func (s *ExecutorEx) Execute(ctx context.Context, stmt string, params ...interface{}) (ret resultset.Result, e error) {
-	r, err := s.proxy.Call(Caller(), ctx, stmt, params)
-	if err != nil {
-		return nil, err
-	}
-	if r[0] != nil {
-		ret = r[0].(resultset.Result)
-	}
-	if r[1] != nil {
-		e = r[1].(error)
-	}
-	return
-}
-
-func (s *ExecutorEx) Begin(ctx context.Context) (e error) {
-	r, err := s.proxy.Call(Caller(), ctx)
-	if err != nil {
-		return err
-	}
-	if r[0] != nil {
-		e = r[0].(error)
-	}
-	return
-}
-
-func (s *ExecutorEx) Commit(ctx context.Context, require bool) (e error) {
-	r, err := s.proxy.Call(Caller(), ctx, require)
-	if err != nil {
-		return err
-	}
-	if r[0] != nil {
-		e = r[0].(error)
-	}
-	return
-}
-
-func (s *ExecutorEx) Rollback(ctx context.Context, require bool) (e error) {
-	r, err := s.proxy.Call(Caller(), ctx, require)
-	if err != nil {
-		return err
-	}
-	if r[0] != nil {
-		e = r[0].(error)
-	}
-	return
-}
-
-func (s *ExecutorEx) Close(ctx context.Context, rollback bool) (e error) {
-	r, err := s.proxy.Call(Caller(), ctx, rollback)
-	if err != nil {
-		return err
-	}
-	if r[0] != nil {
-		e = r[0].(error)
-	}
-	return
-}
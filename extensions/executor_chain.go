@@ -0,0 +1,93 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package extensions
+
+import (
+	"context"
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/executor"
+	"github.com/xfali/lean/resultset"
+)
+
+// ExecutorChain is an executor.Executor wrapped by typed interceptors without reflection.
+// Use and Extend are not concurrency safe, register interceptors before use.
+type ExecutorChain struct {
+	exec         executor.Executor
+	interceptors []Interceptor
+	funcs        chainFuncs
+}
+
+func NewExecutorChain(exec executor.Executor, interceptors ...Interceptor) *ExecutorChain {
+	ret := &ExecutorChain{
+		exec: exec,
+	}
+	return ret.Use(interceptors...)
+}
+
+// Use appends interceptors, the interceptor registered first is called first.
+func (s *ExecutorChain) Use(interceptors ...Interceptor) *ExecutorChain {
+	s.interceptors = append(s.interceptors, interceptors...)
+	s.funcs = chainFuncs{
+		ping:         s.exec.Ping,
+		query:        s.exec.Query,
+		execute:      s.exec.Execute,
+		executeBatch: s.exec.ExecuteBatch,
+		begin:        s.exec.Begin,
+		commit:       s.exec.Commit,
+		rollback:     s.exec.Rollback,
+		close:        s.exec.Close,
+	}.wrap(s.interceptors)
+	return s
+}
+
+// Extend adapts aop advice to Interceptor, advice receives the flattened arguments of executor method.
+func (s *ExecutorChain) Extend(cut aop.PointCut, advice aop.Advice) Extension {
+	s.Use(adviceInterceptor(s.exec, cut, advice, false))
+	return s
+}
+
+func (s *ExecutorChain) Ping(ctx context.Context) bool {
+	return s.funcs.ping(ctx)
+}
+
+func (s *ExecutorChain) Query(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	return s.funcs.query(ctx, stmt, params...)
+}
+
+func (s *ExecutorChain) Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	return s.funcs.execute(ctx, stmt, params...)
+}
+
+func (s *ExecutorChain) ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error) {
+	return s.funcs.executeBatch(ctx, stmt, params)
+}
+
+func (s *ExecutorChain) Begin(ctx context.Context) error {
+	return s.funcs.begin(ctx)
+}
+
+func (s *ExecutorChain) Commit(ctx context.Context, require bool) error {
+	return s.funcs.commit(ctx, require)
+}
+
+func (s *ExecutorChain) Rollback(ctx context.Context, require bool) error {
+	return s.funcs.rollback(ctx, require)
+}
+
+func (s *ExecutorChain) Close(ctx context.Context, rollback bool) error {
+	return s.funcs.close(ctx, rollback)
+}
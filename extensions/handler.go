@@ -0,0 +1,119 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package extensions
+
+import (
+	"context"
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/handler"
+	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/statement"
+)
+
+// HandlerEx extends handler.Handler by aop advice, statements prepared by it are extended by the same advice.
+// HandlerEx is comparable, handlers extending the same handler with the same advice are equal so that they can
+// be used as the key of handler.StatementPool.
+type HandlerEx struct {
+	h        handler.Handler
+	advisors *advisors
+}
+
+// TxHandlerEx is the HandlerEx of handler.TxHandler.
+type TxHandlerEx struct {
+	HandlerEx
+}
+
+// NewHandlerEx returns TxHandlerEx if h is handler.TxHandler, otherwise HandlerEx.
+func NewHandlerEx(h handler.Handler) handler.Handler {
+	return newHandlerEx(h, newAdvisors())
+}
+
+func newHandlerEx(h handler.Handler, advisors *advisors) handler.Handler {
+	if h == nil {
+		return nil
+	}
+	ret := HandlerEx{
+		h:        h,
+		advisors: advisors,
+	}
+	if _, ok := h.(handler.TxHandler); ok {
+		return TxHandlerEx{HandlerEx: ret}
+	}
+	return ret
+}
+
+func (h HandlerEx) Extend(cut aop.PointCut, advice aop.Advice) Extension {
+	h.advisors.add(cut, advice)
+	return h
+}
+
+// Unwrap returns the handler extended.
+func (h HandlerEx) Unwrap() handler.Handler {
+	return h.h
+}
+
+func (h HandlerEx) Prepare(ctx context.Context, sql string) (statement.Statement, error) {
+	r := h.advisors.call(h.h, MethodPrepare, []interface{}{ctx, sql}, func(p []interface{}) []interface{} {
+		st, err := h.h.Prepare(ctxAt(p, 0), stringAt(p, 1))
+		return []interface{}{st, err}
+	})
+	return h.statement(valueAt(r, 0)), errorAt(r, 1)
+}
+
+func (h HandlerEx) Query(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	r := h.advisors.call(h.h, MethodQuery, append([]interface{}{ctx, stmt}, params...), func(p []interface{}) []interface{} {
+		r, err := h.h.Query(ctxAt(p, 0), stringAt(p, 1), variadicAt(p, 2)...)
+		return []interface{}{r, err}
+	})
+	return resultAt(r, 0), errorAt(r, 1)
+}
+
+func (h HandlerEx) Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	r := h.advisors.call(h.h, MethodExecute, append([]interface{}{ctx, stmt}, params...), func(p []interface{}) []interface{} {
+		r, err := h.h.Execute(ctxAt(p, 0), stringAt(p, 1), variadicAt(p, 2)...)
+		return []interface{}{r, err}
+	})
+	return resultAt(r, 0), errorAt(r, 1)
+}
+
+func (h HandlerEx) statement(v interface{}) statement.Statement {
+	st, _ := v.(statement.Statement)
+	if st == nil {
+		return nil
+	}
+	if _, ok := st.(*StatementEx); ok {
+		return st
+	}
+	return newStatementEx(st, h.advisors)
+}
+
+func (h TxHandlerEx) Extend(cut aop.PointCut, advice aop.Advice) Extension {
+	h.advisors.add(cut, advice)
+	return h
+}
+
+func (h TxHandlerEx) Parent() handler.Handler {
+	return newHandlerEx(h.h.(handler.TxHandler).Parent(), h.advisors)
+}
+
+// Bind binds the statement prepared by Parent, StatementEx is unwrapped before it is passed to the handler.
+func (h TxHandlerEx) Bind(ctx context.Context, stmt statement.Statement) (statement.Statement, error) {
+	if st, ok := stmt.(*StatementEx); ok {
+		stmt = st.Unwrap()
+	}
+	st, err := h.h.(handler.TxHandler).Bind(ctx, stmt)
+	return h.statement(st), err
+}
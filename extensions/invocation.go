@@ -0,0 +1,163 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package extensions
+
+import (
+	"context"
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/resultset"
+	"strings"
+)
+
+const (
+	MethodPing         = "Ping"
+	MethodQuery        = "Query"
+	MethodExecute      = "Execute"
+	MethodExecuteBatch = "ExecuteBatch"
+	MethodBegin        = "Begin"
+	MethodCommit       = "Commit"
+	MethodRollback     = "Rollback"
+	MethodClose        = "Close"
+	MethodOpen         = "Open"
+	MethodGetSession   = "GetSession"
+	MethodGetHandler   = "GetHandler"
+	MethodPrepare      = "Prepare"
+)
+
+// PointCutStatement matches the methods of session and executor which execute statements: Query, Execute and ExecuteBatch.
+func PointCutStatement() aop.PointCut {
+	return sessionPointCut{cut: aop.PointCutRegExp("", "^(Query|Execute|ExecuteBatch)$", nil, nil)}
+}
+
+// PointCutMethods matches the methods of session and executor by name.
+func PointCutMethods(methods ...string) aop.PointCut {
+	return sessionPointCut{cut: aop.PointCutRegExp("", "^("+strings.Join(methods, "|")+")$", nil, nil)}
+}
+
+// InvocationTarget returns the object whose method is invoked, it is nil if invocation is not created by extensions.
+// Advices shared by sessions, e.g. the advices of ConnectionEx, use it to keep the state of each session.
+func InvocationTarget(invocation aop.Invocation) interface{} {
+	if v, ok := invocation.(interface{ Target() interface{} }); ok {
+		return v.Target()
+	}
+	return nil
+}
+
+// PointCutStatementAndTx matches Query, Execute, ExecuteBatch, Begin, Commit and Rollback of session and executor.
+func PointCutStatementAndTx() aop.PointCut {
+	return sessionPointCut{cut: aop.PointCutRegExp("", "^(Query|Execute|ExecuteBatch|Begin|Commit|Rollback)$", nil, nil)}
+}
+
+// Call is the parsed invocation of Session / Executor methods.
+type Call struct {
+	Method string
+	Ctx    context.Context
+	// Stmt is empty if the method does not execute statement.
+	Stmt string
+	// Params of Query and Execute.
+	Params []interface{}
+	// BatchParams of ExecuteBatch.
+	BatchParams [][]interface{}
+}
+
+// ParseCall parses the params of advice.
+func ParseCall(method string, params []interface{}) Call {
+	ret := Call{
+		Method: method,
+		Ctx:    context.Background(),
+	}
+	if len(params) > 0 {
+		if ctx, ok := params[0].(context.Context); ok && ctx != nil {
+			ret.Ctx = ctx
+		}
+	}
+	switch method {
+	case MethodQuery, MethodExecute:
+		if len(params) > 1 {
+			ret.Stmt, _ = params[1].(string)
+		}
+		// variadic params are flattened
+		if len(params) > 2 {
+			ret.Params = params[2:]
+		}
+	case MethodExecuteBatch:
+		if len(params) > 1 {
+			ret.Stmt, _ = params[1].(string)
+		}
+		if len(params) > 2 {
+			ret.BatchParams, _ = params[2].([][]interface{})
+		}
+	}
+	return ret
+}
+
+// IsStatement returns true if the call executes statement.
+func (c Call) IsStatement() bool {
+	return c.Method == MethodQuery || c.Method == MethodExecute || c.Method == MethodExecuteBatch
+}
+
+// Operation returns the first keyword of statement or the method name in upper case, e.g. SELECT or COMMIT.
+func (c Call) Operation() string {
+	if c.Stmt == "" {
+		return strings.ToUpper(c.Method)
+	}
+	s := strings.TrimSpace(c.Stmt)
+	if i := strings.IndexAny(s, " \t\r\n("); i > 0 {
+		s = s[:i]
+	}
+	return strings.ToUpper(s)
+}
+
+// Outcome is the parsed results of advice.
+type Outcome struct {
+	Result resultset.Result
+	Batch  *resultset.BatchResult
+	Err    error
+}
+
+func ParseOutcome(ret []interface{}) Outcome {
+	var o Outcome
+	for _, v := range ret {
+		switch x := v.(type) {
+		case *resultset.BatchResult:
+			o.Batch = x
+		case resultset.Result:
+			o.Result = x
+		case error:
+			o.Err = x
+		}
+	}
+	return o
+}
+
+// RowsAffected returns rows affected of Execute and ExecuteBatch, -1 if unknown.
+func (o Outcome) RowsAffected(method string) int64 {
+	switch method {
+	case MethodExecute:
+		if o.Result != nil {
+			if n, err := o.Result.RowsAffected(); err == nil {
+				return n
+			}
+		}
+	case MethodExecuteBatch:
+		if o.Batch != nil {
+			n, _ := o.Batch.RowsAffected()
+			return n
+		}
+	}
+	return -1
+}
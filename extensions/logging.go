@@ -0,0 +1,429 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package extensions
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/logger"
+	"github.com/xfali/lean/mapping"
+	"github.com/xfali/xlog"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	RedactedValue = "******"
+)
+
+// RedactFunc returns the value to log of the param, column is empty if it cannot be inferred from statement.
+type RedactFunc func(stmt string, index int, column string, value interface{}) interface{}
+
+type LogOpt func(*logAdvice)
+
+type logAdvice struct {
+	log           xlog.Logger
+	slow          time.Duration
+	sampleRate    float64
+	logParams     bool
+	logBatch      bool
+	redactColumns map[string]bool
+	redactFunc    RedactFunc
+
+	counter uint64
+}
+
+// NewLogAdvice creates advice which logs statement, params, duration, rows affected and error of Query, Execute,
+// ExecuteBatch, Begin, Commit and Rollback with logger.GetLogger().
+// Statements slower than the threshold are logged with WARN and errors with ERROR, both are never sampled out.
+func NewLogAdvice(opts ...LogOpt) aop.Advice {
+	l := &logAdvice{
+		log:        logger.GetLogger(),
+		sampleRate: 1,
+		logParams:  true,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l.advice
+}
+
+// WithLogging extends the Session / Executor with log advice.
+func WithLogging(ext Extension, opts ...LogOpt) Extension {
+	return ext.Extend(PointCutStatementAndTx(), NewLogAdvice(opts...))
+}
+
+func (l *logAdvice) advice(invocation aop.Invocation, params []interface{}) []interface{} {
+	now := time.Now()
+	ret := invocation.Invoke(params)
+	cost := time.Since(now)
+
+	call := ParseCall(invocation.MethodName(), params)
+	outcome := ParseOutcome(ret)
+	slow := l.slow > 0 && cost >= l.slow
+	if outcome.Err == nil && !slow && !l.sampled() {
+		return ret
+	}
+
+	msg := l.format(call, outcome, cost)
+	switch {
+	case outcome.Err != nil:
+		l.log.Errorln(msg)
+	case slow:
+		l.log.Warnln("[slow] " + msg)
+	default:
+		l.log.Infoln(msg)
+	}
+	return ret
+}
+
+// sampled returns true for sampleRate of calls, evenly distributed.
+func (l *logAdvice) sampled() bool {
+	if l.sampleRate >= 1 {
+		return true
+	}
+	if l.sampleRate <= 0 {
+		return false
+	}
+	n := atomic.AddUint64(&l.counter, 1)
+	return math.Floor(float64(n)*l.sampleRate) != math.Floor(float64(n-1)*l.sampleRate)
+}
+
+func (l *logAdvice) format(call Call, outcome Outcome, cost time.Duration) string {
+	sb := strings.Builder{}
+	sb.WriteString(call.Method)
+	if call.Stmt != "" {
+		sb.WriteString(" stmt: ")
+		sb.WriteString(call.Stmt)
+	}
+	if l.logParams {
+		if call.Method == MethodExecuteBatch {
+			sb.WriteString(" batch: ")
+			sb.WriteString(strconv.Itoa(len(call.BatchParams)))
+			if l.logBatch {
+				sb.WriteString(" params: [")
+				for i, v := range call.BatchParams {
+					if i > 0 {
+						sb.WriteString(" ")
+					}
+					sb.WriteString(fmt.Sprint(l.redact(call.Stmt, v)))
+				}
+				sb.WriteString("]")
+			} else if len(call.BatchParams) > 0 {
+				sb.WriteString(" params (first of ")
+				sb.WriteString(strconv.Itoa(len(call.BatchParams)))
+				sb.WriteString("): ")
+				sb.WriteString(fmt.Sprint(l.redact(call.Stmt, call.BatchParams[0])))
+			}
+		} else if len(call.Params) > 0 {
+			sb.WriteString(" params: ")
+			sb.WriteString(fmt.Sprint(l.redact(call.Stmt, call.Params)))
+		}
+	}
+	sb.WriteString(" cost: ")
+	sb.WriteString(cost.String())
+	if n := outcome.RowsAffected(call.Method); n >= 0 {
+		sb.WriteString(" rows: ")
+		sb.WriteString(strconv.FormatInt(n, 10))
+	}
+	if outcome.Err != nil {
+		sb.WriteString(" error: ")
+		sb.WriteString(outcome.Err.Error())
+	}
+	return sb.String()
+}
+
+// redact returns a copy of params with sensitive values replaced.
+func (l *logAdvice) redact(stmt string, params []interface{}) []interface{} {
+	if len(l.redactColumns) == 0 && l.redactFunc == nil {
+		return params
+	}
+	columns := ParamColumns(stmt, params)
+	ret := make([]interface{}, len(params))
+	for i, v := range params {
+		ret[i] = l.redactValue(stmt, i, columns[i], v)
+	}
+	return ret
+}
+
+func (l *logAdvice) redactValue(stmt string, index int, column string, v interface{}) interface{} {
+	if l.redactFunc != nil {
+		return l.redactFunc(stmt, index, column, v)
+	}
+	return redactColumn(l.redactColumns, stmt, column, v)
+}
+
+// RedactColumns returns RedactFunc which replaces the values of columns with RedactedValue, columns are
+// case-insensitive. Values whose column cannot be inferred are redacted too if the statement contains the columns.
+// Entries of map and fields of struct params are redacted by their keys and names.
+func RedactColumns(columns ...string) RedactFunc {
+	m := map[string]bool{}
+	for _, c := range columns {
+		m[strings.ToLower(c)] = true
+	}
+	return func(stmt string, index int, column string, v interface{}) interface{} {
+		return redactColumn(m, stmt, column, v)
+	}
+}
+
+func redactColumn(columns map[string]bool, stmt string, column string, v interface{}) interface{} {
+	if column != "" && columns[strings.ToLower(column)] {
+		return RedactedValue
+	}
+	// redact values of map, e.g. nebula params
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := iter.Key().String()
+			if columns[strings.ToLower(k)] {
+				m[k] = RedactedValue
+			} else {
+				m[k] = iter.Value().Interface()
+			}
+		}
+		return m
+	}
+	if m, ok := redactStruct(columns, v); ok {
+		return m
+	}
+	if column == "" && containsColumn(columns, stmt) {
+		return RedactedValue
+	}
+	return v
+}
+
+// redactStruct returns the exported fields of (pointer of) struct as map with the values of columns replaced,
+// e.g. nebula params, keys are mapping.FieldAliasTagName tag or field name. It returns false if no field is redacted.
+func redactStruct(columns map[string]bool, v interface{}) (map[string]interface{}, bool) {
+	// the column of sql.NamedArg is its name
+	if _, ok := v.(sql.NamedArg); ok {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, false
+	}
+	t := rv.Type()
+	m := make(map[string]interface{}, t.NumField())
+	redacted := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tn, ok := f.Tag.Lookup(mapping.FieldAliasTagName); ok {
+			name = tn
+		}
+		if columns[strings.ToLower(name)] || columns[strings.ToLower(f.Name)] {
+			m[name] = RedactedValue
+			redacted = true
+		} else {
+			m[name] = rv.Field(i).Interface()
+		}
+	}
+	return m, redacted
+}
+
+// containsColumn returns true if one of the words of stmt is in columns.
+func containsColumn(columns map[string]bool, stmt string) bool {
+	for _, w := range wordRegexp.FindAllString(stmt, -1) {
+		if columns[strings.ToLower(w)] {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	insertColumnsRegexp = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+[^\s(]+\s*\(([^)]*)\)\s*VALUES`)
+	placeholderRegexp   = regexp.MustCompile(`\?|\$\d+`)
+	// column on the left side of comparison, the param may be in IN list or wrapped by functions, e.g. md5(?)
+	compareRegexp = regexp.MustCompile("(?i)([A-Za-z_`\"][\\w.`\"]*)\\s*(?:=|<>|!=|<=|>=|<|>|\\sLIKE|\\sIN\\s*\\((?:\\s*(?:\\?|\\$\\d+)\\s*,)*)\\s*(?:[A-Za-z_]\\w*\\s*\\(\\s*)*$")
+	wordRegexp    = regexp.MustCompile(`[A-Za-z_]\w*`)
+)
+
+// ParamColumns infers the column of each param from statement: the column of INSERT at the position of the param in
+// VALUES tuple, the left side of comparisons, the name of sql.NamedArg, and the key of nebula key-value pairs.
+// It is empty if unknown.
+func ParamColumns(stmt string, params []interface{}) []string {
+	ret := make([]string, len(params))
+
+	// nebula key-value pairs: key is string and $key is in statement
+	if len(params)%2 == 0 && len(params) > 0 {
+		pairs := true
+		for i := 0; i < len(params); i += 2 {
+			k, ok := params[i].(string)
+			if !ok || !strings.Contains(stmt, "$"+k) {
+				pairs = false
+				break
+			}
+		}
+		if pairs {
+			for i := 0; i < len(params); i += 2 {
+				ret[i+1] = params[i].(string)
+			}
+			return ret
+		}
+	}
+
+	values := valuesColumns(stmt)
+	seq := 0
+	for _, loc := range placeholderRegexp.FindAllStringIndex(stmt, -1) {
+		index := seq
+		if p := stmt[loc[0]:loc[1]]; p != "?" {
+			n, _ := strconv.Atoi(p[1:])
+			index = n - 1
+		} else {
+			seq++
+		}
+		if index < 0 || index >= len(ret) {
+			continue
+		}
+		if column, ok := values[loc[0]]; ok {
+			ret[index] = column
+		} else if m := compareRegexp.FindStringSubmatch(stmt[:loc[0]]); m != nil {
+			ret[index] = trimIdentifier(m[1])
+		}
+	}
+
+	for i, v := range params {
+		if arg, ok := v.(sql.NamedArg); ok {
+			ret[i] = arg.Name
+		}
+	}
+	return ret
+}
+
+// valuesColumns maps the offsets of placeholders in VALUES tuples of INSERT to the columns at the same position.
+func valuesColumns(stmt string) map[int]string {
+	m := insertColumnsRegexp.FindStringSubmatchIndex(stmt)
+	if m == nil {
+		return nil
+	}
+	cols := strings.Split(stmt[m[2]:m[3]], ",")
+	ret := map[int]string{}
+	depth, pos := 0, 0
+	var quote byte
+	for i := m[1]; i < len(stmt); i++ {
+		c := stmt[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			if depth == 0 {
+				pos = 0
+			}
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 1:
+			pos++
+		case c == '?' || (c == '$' && i+1 < len(stmt) && stmt[i+1] >= '0' && stmt[i+1] <= '9'):
+			if depth > 0 && pos < len(cols) {
+				ret[i] = trimIdentifier(cols[pos])
+			}
+		case depth == 0 && c != ',' && c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			// end of VALUES tuples, e.g. ON DUPLICATE KEY UPDATE
+			return ret
+		}
+	}
+	return ret
+}
+
+// trimIdentifier removes quotes and table qualifier of column.
+func trimIdentifier(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "."); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.Trim(s, "`\"[]")
+}
+
+type logOpts struct{}
+
+var LogOpts logOpts
+
+func (logOpts) SetLogger(log xlog.Logger) LogOpt {
+	return func(l *logAdvice) {
+		l.log = log
+	}
+}
+
+// SetSlowThreshold logs statements which cost more than threshold with WARN, 0 disables it.
+func (logOpts) SetSlowThreshold(threshold time.Duration) LogOpt {
+	return func(l *logAdvice) {
+		l.slow = threshold
+	}
+}
+
+// SetSampleRate logs rate (0 to 1) of successful calls which are not slow, default is 1.
+func (logOpts) SetSampleRate(rate float64) LogOpt {
+	return func(l *logAdvice) {
+		l.sampleRate = rate
+	}
+}
+
+// SetLogParams enables logging params, default is true.
+func (logOpts) SetLogParams(enable bool) LogOpt {
+	return func(l *logAdvice) {
+		l.logParams = enable
+	}
+}
+
+// SetLogBatchParams logs every params set of ExecuteBatch if SetLogParams, default only the first is logged.
+func (logOpts) SetLogBatchParams(enable bool) LogOpt {
+	return func(l *logAdvice) {
+		l.logBatch = enable
+	}
+}
+
+// SetRedactColumns replaces the values of columns (case-insensitive) with RedactedValue, see RedactColumns.
+func (logOpts) SetRedactColumns(columns ...string) LogOpt {
+	return func(l *logAdvice) {
+		l.redactColumns = map[string]bool{}
+		for _, c := range columns {
+			l.redactColumns[strings.ToLower(c)] = true
+		}
+	}
+}
+
+// SetRedactFunc sets the function to redact params, SetRedactColumns is ignored if it is set.
+func (logOpts) SetRedactFunc(f RedactFunc) LogOpt {
+	return func(l *logAdvice) {
+		l.redactFunc = f
+	}
+}
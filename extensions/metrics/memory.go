@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// HistogramValue is the count and sum of observations.
+type HistogramValue struct {
+	Count uint64
+	Sum   float64
+}
+
+// MemoryCollector keeps metrics in memory, it is useful for testing and debugging.
+type MemoryCollector struct {
+	locker     sync.Mutex
+	histograms map[string]*HistogramValue
+	counters   map[string]float64
+	gauges     map[string]float64
+}
+
+func NewMemoryCollector() *MemoryCollector {
+	return &MemoryCollector{
+		histograms: map[string]*HistogramValue{},
+		counters:   map[string]float64{},
+		gauges:     map[string]float64{},
+	}
+}
+
+// Key returns the key of metric, e.g. name{a="1",b="2"}.
+func Key(name string, labels Labels) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	sb := strings.Builder{}
+	sb.WriteString(name)
+	sb.WriteString("{")
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(k)
+		sb.WriteString(`="`)
+		sb.WriteString(labels[k])
+		sb.WriteString(`"`)
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func (c *MemoryCollector) ObserveHistogram(name string, labels Labels, value float64) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	key := Key(name, labels)
+	h, ok := c.histograms[key]
+	if !ok {
+		h = &HistogramValue{}
+		c.histograms[key] = h
+	}
+	h.Count++
+	h.Sum += value
+}
+
+func (c *MemoryCollector) AddCounter(name string, labels Labels, value float64) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.counters[Key(name, labels)] += value
+}
+
+func (c *MemoryCollector) SetGauge(name string, labels Labels, value float64) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.gauges[Key(name, labels)] = value
+}
+
+func (c *MemoryCollector) Histogram(name string, labels Labels) HistogramValue {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	if h, ok := c.histograms[Key(name, labels)]; ok {
+		return *h
+	}
+	return HistogramValue{}
+}
+
+func (c *MemoryCollector) Counter(name string, labels Labels) float64 {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.counters[Key(name, labels)]
+}
+
+func (c *MemoryCollector) Gauge(name string, labels Labels) float64 {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.gauges[Key(name, labels)]
+}
@@ -0,0 +1,348 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"context"
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/connection"
+	"github.com/xfali/lean/extensions"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	StatementDuration = "lean_statement_duration_seconds"
+	TransactionTotal  = "lean_transactions_total"
+
+	PoolUp                = "lean_pool_up"
+	PoolMaxOpen           = "lean_pool_max_open_connections"
+	PoolOpen              = "lean_pool_open_connections"
+	PoolInUse             = "lean_pool_in_use_connections"
+	PoolIdle              = "lean_pool_idle_connections"
+	PoolWaitCount         = "lean_pool_wait_count"
+	PoolWaitSeconds       = "lean_pool_wait_seconds"
+	PoolMaxIdleClosed     = "lean_pool_max_idle_closed"
+	PoolMaxLifetimeClosed = "lean_pool_max_lifetime_closed"
+
+	LabelMethod      = "method"
+	LabelOperation   = "operation"
+	LabelFingerprint = "fingerprint"
+	LabelStatus      = "status"
+	LabelOutcome     = "outcome"
+	LabelDataSource  = "datasource"
+
+	StatusOK    = "ok"
+	StatusError = "error"
+
+	// OtherFingerprint replaces fingerprints over the limit of SetMaxFingerprints.
+	OtherFingerprint = "other"
+
+	// DefaultMaxFingerprints is the default limit of distinct fingerprints.
+	DefaultMaxFingerprints = 1000
+	// DefaultReportInterval is the interval of PoolReporter if the interval is not positive.
+	DefaultReportInterval = 15 * time.Second
+)
+
+// Help is the description of metrics.
+var Help = map[string]string{
+	StatementDuration:     "Duration of statements in seconds.",
+	TransactionTotal:      "Transactions by outcome.",
+	PoolUp:                "1 if the backend is available.",
+	PoolMaxOpen:           "Max open connections of pool.",
+	PoolOpen:              "Open connections of pool.",
+	PoolInUse:             "Connections in use.",
+	PoolIdle:              "Idle connections of pool.",
+	PoolWaitCount:         "Total number of connections waited for.",
+	PoolWaitSeconds:       "Total time blocked waiting for connections in seconds.",
+	PoolMaxIdleClosed:     "Total number of connections closed due to max idle.",
+	PoolMaxLifetimeClosed: "Total number of connections closed due to max lifetime.",
+}
+
+type Labels map[string]string
+
+// Collector receives metrics, it is implemented by the adapters of metrics systems.
+// Labels of the same metric name always have the same keys.
+type Collector interface {
+	ObserveHistogram(name string, labels Labels, value float64)
+	AddCounter(name string, labels Labels, value float64)
+	SetGauge(name string, labels Labels, value float64)
+}
+
+type Opt func(*advice)
+
+type advice struct {
+	collector       Collector
+	fingerprint     bool
+	maxFingerprints int
+	labels          Labels
+
+	locker       sync.Mutex
+	fingerprints map[string]struct{}
+}
+
+// NewAdvice creates advice which records the duration of Query, Execute and ExecuteBatch by method, operation,
+// fingerprint and status, and counts transactions by outcome: commit, rollback, begin_error, commit_error and
+// rollback_error.
+func NewAdvice(collector Collector, opts ...Opt) aop.Advice {
+	a := &advice{
+		collector:       collector,
+		fingerprint:     true,
+		maxFingerprints: DefaultMaxFingerprints,
+		fingerprints:    map[string]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a.advice
+}
+
+// WithMetrics extends the Session / Executor with metrics advice.
+func WithMetrics(ext extensions.Extension, collector Collector, opts ...Opt) extensions.Extension {
+	return ext.Extend(extensions.PointCutStatementAndTx(), NewAdvice(collector, opts...))
+}
+
+func (a *advice) advice(invocation aop.Invocation, params []interface{}) []interface{} {
+	now := time.Now()
+	ret := invocation.Invoke(params)
+	cost := time.Since(now)
+
+	call := extensions.ParseCall(invocation.MethodName(), params)
+	err := extensions.ParseOutcome(ret).Err
+	if call.IsStatement() {
+		status := StatusOK
+		if err != nil {
+			status = StatusError
+		}
+		labels := a.newLabels(4)
+		labels[LabelMethod] = call.Method
+		labels[LabelOperation] = call.Operation()
+		labels[LabelStatus] = status
+		if a.fingerprint {
+			labels[LabelFingerprint] = a.fingerprintOf(call.Stmt)
+		}
+		a.collector.ObserveHistogram(StatementDuration, labels, cost.Seconds())
+		return ret
+	}
+
+	outcome := ""
+	switch call.Method {
+	case extensions.MethodBegin:
+		if err != nil {
+			outcome = "begin_error"
+		}
+	case extensions.MethodCommit:
+		outcome = "commit"
+	case extensions.MethodRollback:
+		outcome = "rollback"
+	}
+	if outcome != "" {
+		if err != nil && call.Method != extensions.MethodBegin {
+			outcome += "_error"
+		}
+		labels := a.newLabels(1)
+		labels[LabelOutcome] = outcome
+		a.collector.AddCounter(TransactionTotal, labels, 1)
+	}
+	return ret
+}
+
+func (a *advice) newLabels(n int) Labels {
+	ret := make(Labels, n+len(a.labels))
+	for k, v := range a.labels {
+		ret[k] = v
+	}
+	return ret
+}
+
+func (a *advice) fingerprintOf(stmt string) string {
+	fp := Fingerprint(stmt)
+	if a.maxFingerprints <= 0 {
+		return fp
+	}
+	a.locker.Lock()
+	defer a.locker.Unlock()
+	if _, ok := a.fingerprints[fp]; ok {
+		return fp
+	}
+	if len(a.fingerprints) >= a.maxFingerprints {
+		return OtherFingerprint
+	}
+	a.fingerprints[fp] = struct{}{}
+	return fp
+}
+
+var (
+	fingerprintLiteral = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|\b\d+(?:\.\d+)?\b|\$\d+|\?`)
+	fingerprintList    = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	fingerprintSpace   = regexp.MustCompile(`\s+`)
+)
+
+// Fingerprint normalizes statement by replacing literals and placeholders with ? and collapsing IN lists and spaces,
+// statements differ only in values have the same fingerprint.
+func Fingerprint(stmt string) string {
+	s := fingerprintLiteral.ReplaceAllString(stmt, "?")
+	s = fingerprintList.ReplaceAllString(s, "(?)")
+	s = fingerprintSpace.ReplaceAllString(s, " ")
+	return strings.TrimSpace(s)
+}
+
+// ReportPoolStats sets the gauges of pool statistics with datasource label.
+func ReportPoolStats(collector Collector, datasource string, stats connection.PoolStats) {
+	labels := Labels{LabelDataSource: datasource}
+	up := 0.0
+	if stats.Up {
+		up = 1
+	}
+	collector.SetGauge(PoolUp, labels, up)
+	collector.SetGauge(PoolMaxOpen, labels, float64(stats.MaxOpen))
+	collector.SetGauge(PoolOpen, labels, float64(stats.Open))
+	collector.SetGauge(PoolInUse, labels, float64(stats.InUse))
+	collector.SetGauge(PoolIdle, labels, float64(stats.Idle))
+	collector.SetGauge(PoolWaitCount, labels, float64(stats.WaitCount))
+	collector.SetGauge(PoolWaitSeconds, labels, stats.WaitDuration.Seconds())
+	collector.SetGauge(PoolMaxIdleClosed, labels, float64(stats.MaxIdleClosed))
+	collector.SetGauge(PoolMaxLifetimeClosed, labels, float64(stats.MaxLifetimeClosed))
+}
+
+// PoolReporter reports statistics of pools periodically.
+type PoolReporter struct {
+	collector Collector
+	interval  time.Duration
+
+	locker    sync.Mutex
+	providers map[string]connection.StatsProvider
+	cancel    context.CancelFunc
+	wait      sync.WaitGroup
+}
+
+// NewPoolReporter creates a reporter reports every interval after Start, DefaultReportInterval is used if
+// interval is not positive.
+func NewPoolReporter(collector Collector, interval time.Duration) *PoolReporter {
+	if interval <= 0 {
+		interval = DefaultReportInterval
+	}
+	return &PoolReporter{
+		collector: collector,
+		interval:  interval,
+		providers: map[string]connection.StatsProvider{},
+	}
+}
+
+// Add adds the connection if it implements connection.StatsProvider, returns false otherwise.
+// Connections of extensions.ConnectionEx, read-write and shard connections implement it.
+func (r *PoolReporter) Add(datasource string, conn connection.Connection) bool {
+	p, ok := conn.(connection.StatsProvider)
+	if !ok {
+		return false
+	}
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	r.providers[datasource] = p
+	return true
+}
+
+// AddManager adds all connections of the manager.
+func (r *PoolReporter) AddManager(m *connection.Manager) {
+	for _, name := range m.Names() {
+		if conn, ok := m.Get(name); ok {
+			r.Add(name, conn)
+		}
+	}
+}
+
+// Report reports statistics of all pools once.
+func (r *PoolReporter) Report() {
+	r.locker.Lock()
+	names := make([]string, 0, len(r.providers))
+	for k := range r.providers {
+		names = append(names, k)
+	}
+	r.locker.Unlock()
+	sort.Strings(names)
+	for _, name := range names {
+		r.locker.Lock()
+		p := r.providers[name]
+		r.locker.Unlock()
+		ReportPoolStats(r.collector, name, p.PoolStats())
+	}
+}
+
+func (r *PoolReporter) Start() {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	if r.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wait.Add(1)
+	go func() {
+		defer r.wait.Done()
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		r.Report()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Report()
+			}
+		}
+	}()
+}
+
+func (r *PoolReporter) Stop() {
+	r.locker.Lock()
+	cancel := r.cancel
+	r.cancel = nil
+	r.locker.Unlock()
+	if cancel != nil {
+		cancel()
+		r.wait.Wait()
+	}
+}
+
+type opts struct{}
+
+var Opts opts
+
+// SetFingerprintEnabled records fingerprint label, default is true.
+func (opts) SetFingerprintEnabled(enable bool) Opt {
+	return func(a *advice) {
+		a.fingerprint = enable
+	}
+}
+
+// SetMaxFingerprints limits distinct fingerprints to avoid high cardinality, the rest are recorded as "other".
+// Default is DefaultMaxFingerprints, n <= 0 means no limit.
+func (opts) SetMaxFingerprints(n int) Opt {
+	return func(a *advice) {
+		a.maxFingerprints = n
+	}
+}
+
+// SetLabels adds constant labels to all metrics of the advice, e.g. datasource.
+func (opts) SetLabels(labels Labels) Opt {
+	return func(a *advice) {
+		a.labels = labels
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/xfali/lean/connection"
+	"github.com/xfali/lean/extensions"
+	"github.com/xfali/lean/session"
+	"testing"
+)
+
+type testSession struct {
+	session.Session
+}
+
+func (s *testSession) Commit(ctx context.Context) error {
+	return errors.New("commit failed")
+}
+
+func TestFingerprint(t *testing.T) {
+	for stmt, expect := range map[string]string{
+		"select * from tbl where id = 1 and name = 'a''b'":   "select * from tbl where id = ? and name = ?",
+		"select *  from tbl\n where id in (1, 2, 3)":         "select * from tbl where id in (?)",
+		"select * from tbl1 where id in (?, ?) and age > $1": "select * from tbl1 where id in (?) and age > ?",
+	} {
+		if v := Fingerprint(stmt); v != expect {
+			t.Fatal("expect ", expect, " but get ", v)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	collector := NewMemoryCollector()
+	sess := extensions.NewSessionEx(&testSession{Session: session.NewDummySession(0)})
+	WithMetrics(sess, collector, Opts.SetLabels(Labels{LabelDataSource: "orders"}), Opts.SetMaxFingerprints(1))
+
+	ctx := context.Background()
+	sess.Query(ctx, "select * from tbl where id = ?", 1)
+	sess.Query(ctx, "select * from tbl where id = ?", 2)
+	sess.Execute(ctx, "update tbl set name = 'x'")
+	sess.Begin(ctx)
+	sess.Commit(ctx)
+	sess.Begin(ctx)
+	sess.Rollback(ctx)
+
+	h := collector.Histogram(StatementDuration, Labels{
+		LabelDataSource:  "orders",
+		LabelMethod:      "Query",
+		LabelOperation:   "SELECT",
+		LabelStatus:      StatusOK,
+		LabelFingerprint: "select * from tbl where id = ?",
+	})
+	if h.Count != 2 {
+		t.Fatal("expect 2 queries but get ", h)
+	}
+	h = collector.Histogram(StatementDuration, Labels{
+		LabelDataSource:  "orders",
+		LabelMethod:      "Execute",
+		LabelOperation:   "UPDATE",
+		LabelStatus:      StatusOK,
+		LabelFingerprint: OtherFingerprint,
+	})
+	if h.Count != 1 {
+		t.Fatal("expect fingerprint over limit recorded as other ", h)
+	}
+	if v := collector.Counter(TransactionTotal, Labels{LabelDataSource: "orders", LabelOutcome: "commit_error"}); v != 1 {
+		t.Fatal("expect 1 commit error but get ", v)
+	}
+	if v := collector.Counter(TransactionTotal, Labels{LabelDataSource: "orders", LabelOutcome: "rollback"}); v != 1 {
+		t.Fatal("expect 1 rollback but get ", v)
+	}
+}
+
+type testConnection struct {
+	connection.Connection
+}
+
+func (c *testConnection) PoolStats() connection.PoolStats {
+	return connection.PoolStats{Up: true, MaxOpen: 10, InUse: 3}
+}
+
+func TestPoolReporter(t *testing.T) {
+	collector := NewMemoryCollector()
+	r := NewPoolReporter(collector, 0)
+	if !r.Add("orders", &testConnection{}) {
+		t.Fatal("expect stats provider")
+	}
+	r.Report()
+	labels := Labels{LabelDataSource: "orders"}
+	if collector.Gauge(PoolUp, labels) != 1 || collector.Gauge(PoolMaxOpen, labels) != 10 || collector.Gauge(PoolInUse, labels) != 3 {
+		t.Fatal("unexpected gauges")
+	}
+}
+
+func TestPoolReporterWrapped(t *testing.T) {
+	collector := NewMemoryCollector()
+	r := NewPoolReporter(collector, -1)
+	for name, conn := range map[string]connection.Connection{
+		"ex": extensions.NewConnectionEx(&testConnection{}),
+		"rw": connection.NewReadWriteConnection(&testConnection{}, []connection.Connection{&testConnection{}}),
+		"shard": connection.NewShardConnection([]connection.Connection{
+			&testConnection{}, extensions.NewConnectionEx(&testConnection{})}),
+	} {
+		if !r.Add(name, conn) {
+			t.Fatal("expect stats provider of ", name)
+		}
+	}
+	r.Start()
+	r.Stop()
+	for name, inUse := range map[string]float64{"ex": 3, "rw": 6, "shard": 6} {
+		labels := Labels{LabelDataSource: name}
+		if collector.Gauge(PoolUp, labels) != 1 || collector.Gauge(PoolInUse, labels) != inUse {
+			t.Fatal("unexpected gauges of ", name, collector.Gauge(PoolUp, labels), collector.Gauge(PoolInUse, labels))
+		}
+	}
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package prom adapts metrics.Collector to Prometheus.
+package prom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xfali/lean/extensions/metrics"
+	"sort"
+	"sync"
+)
+
+type Opt func(*collector)
+
+type collector struct {
+	registerer prometheus.Registerer
+	namespace  string
+	buckets    []float64
+
+	locker     sync.Mutex
+	histograms map[string]*prometheus.HistogramVec
+	counters   map[string]*prometheus.CounterVec
+	gauges     map[string]*prometheus.GaugeVec
+}
+
+// NewCollector creates metrics.Collector which registers vectors to registerer when the metric is first reported,
+// label names are taken from the first report.
+func NewCollector(registerer prometheus.Registerer, opts ...Opt) *collector {
+	ret := &collector{
+		registerer: registerer,
+		buckets:    prometheus.DefBuckets,
+		histograms: map[string]*prometheus.HistogramVec{},
+		counters:   map[string]*prometheus.CounterVec{},
+		gauges:     map[string]*prometheus.GaugeVec{},
+	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
+}
+
+func labelNames(labels metrics.Labels) []string {
+	ret := make([]string, 0, len(labels))
+	for k := range labels {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// register registers the collector, the registered one is returned if it is registered already.
+func (c *collector) register(v prometheus.Collector) prometheus.Collector {
+	if err := c.registerer.Register(v); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+		return nil
+	}
+	return v
+}
+
+func (c *collector) ObserveHistogram(name string, labels metrics.Labels, value float64) {
+	c.locker.Lock()
+	vec, ok := c.histograms[name]
+	if !ok {
+		v := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: c.namespace,
+			Name:      name,
+			Help:      metrics.Help[name],
+			Buckets:   c.buckets,
+		}, labelNames(labels))
+		vec, _ = c.register(v).(*prometheus.HistogramVec)
+		c.histograms[name] = vec
+	}
+	c.locker.Unlock()
+	if vec == nil {
+		return
+	}
+	if o, err := vec.GetMetricWith(prometheus.Labels(labels)); err == nil {
+		o.Observe(value)
+	}
+}
+
+func (c *collector) AddCounter(name string, labels metrics.Labels, value float64) {
+	c.locker.Lock()
+	vec, ok := c.counters[name]
+	if !ok {
+		v := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Name:      name,
+			Help:      metrics.Help[name],
+		}, labelNames(labels))
+		vec, _ = c.register(v).(*prometheus.CounterVec)
+		c.counters[name] = vec
+	}
+	c.locker.Unlock()
+	if vec == nil {
+		return
+	}
+	if o, err := vec.GetMetricWith(prometheus.Labels(labels)); err == nil {
+		o.Add(value)
+	}
+}
+
+func (c *collector) SetGauge(name string, labels metrics.Labels, value float64) {
+	c.locker.Lock()
+	vec, ok := c.gauges[name]
+	if !ok {
+		v := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: c.namespace,
+			Name:      name,
+			Help:      metrics.Help[name],
+		}, labelNames(labels))
+		vec, _ = c.register(v).(*prometheus.GaugeVec)
+		c.gauges[name] = vec
+	}
+	c.locker.Unlock()
+	if vec == nil {
+		return
+	}
+	if o, err := vec.GetMetricWith(prometheus.Labels(labels)); err == nil {
+		o.Set(value)
+	}
+}
+
+type opts struct{}
+
+var Opts opts
+
+func (opts) SetNamespace(namespace string) Opt {
+	return func(c *collector) {
+		c.namespace = namespace
+	}
+}
+
+// SetBuckets sets the buckets of histograms, default is prometheus.DefBuckets.
+func (opts) SetBuckets(buckets []float64) Opt {
+	return func(c *collector) {
+		c.buckets = buckets
+	}
+}
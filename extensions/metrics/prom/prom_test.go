@@ -0,0 +1,57 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xfali/lean/extensions/metrics"
+	"testing"
+)
+
+func TestCollector(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	c := NewCollector(registry, Opts.SetNamespace("app"))
+	labels := metrics.Labels{metrics.LabelMethod: "Query", metrics.LabelStatus: metrics.StatusOK}
+	c.ObserveHistogram(metrics.StatementDuration, labels, 0.1)
+	c.ObserveHistogram(metrics.StatementDuration, labels, 0.2)
+	c.AddCounter(metrics.TransactionTotal, metrics.Labels{metrics.LabelOutcome: "commit"}, 1)
+	c.SetGauge(metrics.PoolInUse, metrics.Labels{metrics.LabelDataSource: "orders"}, 3)
+	// labels mismatched are dropped
+	c.SetGauge(metrics.PoolInUse, metrics.Labels{"unknown": "x"}, 3)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]float64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			switch {
+			case m.GetHistogram() != nil:
+				values[f.GetName()] = float64(m.GetHistogram().GetSampleCount())
+			case m.GetCounter() != nil:
+				values[f.GetName()] = m.GetCounter().GetValue()
+			case m.GetGauge() != nil:
+				values[f.GetName()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	if values["app_"+metrics.StatementDuration] != 2 || values["app_"+metrics.TransactionTotal] != 1 ||
+		values["app_"+metrics.PoolInUse] != 3 {
+		t.Fatal("unexpected values ", values)
+	}
+}
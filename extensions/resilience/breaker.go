@@ -0,0 +1,222 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resilience
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"github.com/xfali/lean/errors"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultFailureThreshold = 5
+	DefaultOpenTimeout      = 30 * time.Second
+	DefaultHalfOpenRequests = 1
+)
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Classifier returns true if err should be counted as a failure of the backend.
+type Classifier func(err error) bool
+
+// DefaultClassifier counts all errors except the cancellation of caller and the rejections of guards.
+func DefaultClassifier(err error) bool {
+	return err != nil &&
+		!stderrors.Is(err, context.Canceled) &&
+		!stderrors.Is(err, errors.CircuitOpen) &&
+		!stderrors.Is(err, errors.ConcurrencyLimitExceeded) &&
+		!stderrors.Is(err, errors.RateLimitExceeded)
+}
+
+type BreakerOpt func(*Breaker)
+
+// Breaker opens after FailureThreshold consecutive failures and rejects requests with errors.CircuitOpen.
+// After OpenTimeout it turns half-open and lets HalfOpenRequests probes through, it is closed if all of them
+// succeed, otherwise opened again.
+type Breaker struct {
+	threshold        int
+	openTimeout      time.Duration
+	halfOpenRequests int
+	classifier       Classifier
+	onStateChange    func(from, to State)
+	now              func() time.Time
+
+	locker    sync.Mutex
+	state     State
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+}
+
+func NewBreaker(opts ...BreakerOpt) *Breaker {
+	ret := &Breaker{
+		threshold:        DefaultFailureThreshold,
+		openTimeout:      DefaultOpenTimeout,
+		halfOpenRequests: DefaultHalfOpenRequests,
+		classifier:       DefaultClassifier,
+		now:              time.Now,
+	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
+}
+
+func (b *Breaker) State() State {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+func (b *Breaker) Acquire(ctx context.Context) (func(err error), error) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+
+	if b.state == StateOpen {
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return nil, errors.CircuitOpen
+		}
+		b.setState(StateHalfOpen)
+	}
+	if b.state == StateHalfOpen {
+		if b.probes >= b.halfOpenRequests {
+			return nil, errors.CircuitOpen
+		}
+		b.probes++
+		return b.releaseProbe, nil
+	}
+	return b.release, nil
+}
+
+func (b *Breaker) release(err error) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+
+	// request finished after the state changed is ignored
+	if b.state != StateClosed || err == errRejected {
+		return
+	}
+	if !b.classifier(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.open()
+	}
+}
+
+func (b *Breaker) releaseProbe(err error) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+
+	if b.state != StateHalfOpen {
+		return
+	}
+	b.probes--
+	if err == errRejected {
+		return
+	}
+	if b.classifier(err) {
+		b.open()
+		return
+	}
+	b.successes++
+	if b.successes >= b.halfOpenRequests {
+		b.failures = 0
+		b.setState(StateClosed)
+	}
+}
+
+func (b *Breaker) open() {
+	b.openedAt = b.now()
+	b.setState(StateOpen)
+}
+
+func (b *Breaker) setState(state State) {
+	from := b.state
+	b.state = state
+	b.probes = 0
+	b.successes = 0
+	if from != state && b.onStateChange != nil {
+		b.onStateChange(from, state)
+	}
+}
+
+type breakerOpts struct{}
+
+var BreakerOpts breakerOpts
+
+// SetFailureThreshold sets the consecutive failures to open the breaker, default is DefaultFailureThreshold.
+func (breakerOpts) SetFailureThreshold(n int) BreakerOpt {
+	return func(b *Breaker) {
+		b.threshold = n
+	}
+}
+
+// SetOpenTimeout sets the duration of open state before probing, default is DefaultOpenTimeout.
+func (breakerOpts) SetOpenTimeout(timeout time.Duration) BreakerOpt {
+	return func(b *Breaker) {
+		b.openTimeout = timeout
+	}
+}
+
+// SetHalfOpenRequests sets the probes of half-open state, default is DefaultHalfOpenRequests.
+func (breakerOpts) SetHalfOpenRequests(n int) BreakerOpt {
+	return func(b *Breaker) {
+		b.halfOpenRequests = n
+	}
+}
+
+// SetClassifier sets the classifier of failures, default is DefaultClassifier.
+func (breakerOpts) SetClassifier(classifier Classifier) BreakerOpt {
+	return func(b *Breaker) {
+		b.classifier = classifier
+	}
+}
+
+// SetOnStateChange sets the function called with the lock of breaker held when state changes.
+func (breakerOpts) SetOnStateChange(f func(from, to State)) BreakerOpt {
+	return func(b *Breaker) {
+		b.onStateChange = f
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resilience
+
+import (
+	"context"
+	"github.com/xfali/lean/errors"
+	"sync"
+	"time"
+)
+
+type LimiterOpt func(*limiter)
+
+type limiter struct {
+	maxWait time.Duration
+}
+
+// ConcurrencyLimiter limits the statements executing at the same time.
+type ConcurrencyLimiter struct {
+	limiter
+	sem chan struct{}
+}
+
+// NewConcurrencyLimiter creates limiter allowing max concurrent statements, statements over the limit are rejected
+// with errors.ConcurrencyLimitExceeded unless a slot is released within MaxWait.
+func NewConcurrencyLimiter(max int, opts ...LimiterOpt) *ConcurrencyLimiter {
+	ret := &ConcurrencyLimiter{
+		sem: make(chan struct{}, max),
+	}
+	for _, opt := range opts {
+		opt(&ret.limiter)
+	}
+	return ret
+}
+
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) (func(err error), error) {
+	select {
+	case l.sem <- struct{}{}:
+		return l.release, nil
+	default:
+	}
+	if l.maxWait == 0 {
+		return nil, errors.ConcurrencyLimitExceeded
+	}
+	var timeout <-chan time.Time
+	if l.maxWait > 0 {
+		timer := time.NewTimer(l.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case l.sem <- struct{}{}:
+		return l.release, nil
+	case <-timeout:
+		return nil, errors.ConcurrencyLimitExceeded
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InFlight returns the statements executing.
+func (l *ConcurrencyLimiter) InFlight() int {
+	return len(l.sem)
+}
+
+func (l *ConcurrencyLimiter) release(err error) {
+	<-l.sem
+}
+
+// RateLimiter is a token bucket limiting the statements per second.
+type RateLimiter struct {
+	limiter
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	locker sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates token bucket filled with rate tokens per second up to burst, statements are rejected
+// with errors.RateLimitExceeded unless a token is available within MaxWait.
+func NewRateLimiter(rate float64, burst int, opts ...LimiterOpt) *RateLimiter {
+	ret := &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(&ret.limiter)
+	}
+	ret.last = ret.now()
+	return ret
+}
+
+func (l *RateLimiter) Acquire(ctx context.Context) (func(err error), error) {
+	wait, err := l.reserve()
+	if err != nil || wait == 0 {
+		return noRelease, err
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return noRelease, nil
+	case <-ctx.Done():
+		l.locker.Lock()
+		l.tokens++
+		l.locker.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// reserve takes a token and returns the duration to wait for it.
+func (l *RateLimiter) reserve() (time.Duration, error) {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0, nil
+	}
+	if l.rate <= 0 || l.maxWait == 0 {
+		return 0, errors.RateLimitExceeded
+	}
+	wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	if l.maxWait > 0 && wait > l.maxWait {
+		return 0, errors.RateLimitExceeded
+	}
+	l.tokens--
+	return wait, nil
+}
+
+func noRelease(err error) {}
+
+type limiterOpts struct{}
+
+var LimiterOpts limiterOpts
+
+// SetMaxWait sets the max duration waiting for a slot or token, 0 (default) rejects immediately and negative waits
+// until ctx is done.
+func (limiterOpts) SetMaxWait(maxWait time.Duration) LimiterOpt {
+	return func(l *limiter) {
+		l.maxWait = maxWait
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resilience
+
+import (
+	"context"
+	stderrors "errors"
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/extensions"
+	"regexp"
+)
+
+// Guard guards the execution of statements: Acquire returns error to reject the statement, otherwise release
+// must be called with the error of the statement after it is finished.
+type Guard interface {
+	Acquire(ctx context.Context) (release func(err error), err error)
+}
+
+// errRejected is passed to release if the statement is rejected by the guards acquired after it.
+var errRejected = stderrors.New("rejected by other guard")
+
+type rule struct {
+	pattern *regexp.Regexp
+	guards  []Guard
+}
+
+type Opt func(*advice)
+
+type advice struct {
+	rules []rule
+}
+
+// NewAdvice creates advice which guards Query, Execute and ExecuteBatch. Guards added by AddGuards apply to all
+// statements, guards of rules apply to the statements matching the pattern, they are acquired in the order added.
+// Guards are shared by the sessions extended by the advice, e.g. extend connection.Connection with
+// extensions.ConnectionEx to guard the connection.
+func NewAdvice(opts ...Opt) aop.Advice {
+	a := &advice{}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a.advice
+}
+
+// WithResilience extends the Connection / Session / Executor with resilience advice.
+func WithResilience(ext extensions.Extension, opts ...Opt) extensions.Extension {
+	return ext.Extend(extensions.PointCutStatement(), NewAdvice(opts...))
+}
+
+func (a *advice) advice(invocation aop.Invocation, params []interface{}) []interface{} {
+	call := extensions.ParseCall(invocation.MethodName(), params)
+	var releases []func(error)
+	for _, r := range a.rules {
+		if r.pattern != nil && !r.pattern.MatchString(call.Stmt) {
+			continue
+		}
+		for _, g := range r.guards {
+			release, err := g.Acquire(call.Ctx)
+			if err != nil {
+				for i := len(releases) - 1; i >= 0; i-- {
+					releases[i](errRejected)
+				}
+				return []interface{}{nil, err}
+			}
+			releases = append(releases, release)
+		}
+	}
+	ret := invocation.Invoke(params)
+	err := extensions.ParseOutcome(ret).Err
+	for i := len(releases) - 1; i >= 0; i-- {
+		releases[i](err)
+	}
+	return ret
+}
+
+type opts struct{}
+
+var Opts opts
+
+// AddGuards adds guards of all statements.
+func (opts) AddGuards(guards ...Guard) Opt {
+	return func(a *advice) {
+		a.rules = append(a.rules, rule{guards: guards})
+	}
+}
+
+// AddRule adds guards of the statements matching the regular expression pattern, it panics if pattern is invalid.
+func (opts) AddRule(pattern string, guards ...Guard) Opt {
+	return func(a *advice) {
+		a.rules = append(a.rules, rule{pattern: regexp.MustCompile(pattern), guards: guards})
+	}
+}
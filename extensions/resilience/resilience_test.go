@@ -0,0 +1,183 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resilience
+
+import (
+	"context"
+	stderrors "errors"
+	"github.com/xfali/lean/errors"
+	"github.com/xfali/lean/extensions"
+	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/session"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func TestBreaker(t *testing.T) {
+	c := &clock{now: time.Now()}
+	var states []State
+	b := NewBreaker(BreakerOpts.SetFailureThreshold(2),
+		BreakerOpts.SetOpenTimeout(time.Second),
+		BreakerOpts.SetOnStateChange(func(from, to State) {
+			states = append(states, to)
+		}))
+	b.now = c.Now
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		release, err := b.Acquire(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// success resets consecutive failures
+		if i == 1 {
+			release(nil)
+		} else {
+			release(fail)
+		}
+	}
+	if b.State() != StateClosed {
+		t.Fatal("expect closed but get ", b.State())
+	}
+	release, _ := b.Acquire(ctx)
+	release(fail)
+	if _, err := b.Acquire(ctx); !stderrors.Is(err, errors.CircuitOpen) {
+		t.Fatal("expect circuit open but get ", err)
+	}
+
+	c.now = c.now.Add(time.Second)
+	probe, err := b.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Acquire(ctx); !stderrors.Is(err, errors.CircuitOpen) {
+		t.Fatal("expect one probe but get ", err)
+	}
+	probe(fail)
+	if b.State() != StateOpen {
+		t.Fatal("expect open but get ", b.State())
+	}
+
+	c.now = c.now.Add(time.Second)
+	probe, _ = b.Acquire(ctx)
+	probe(context.Canceled)
+	if b.State() != StateClosed {
+		t.Fatal("expect closed but get ", b.State())
+	}
+	if len(states) != 5 || states[0] != StateOpen || states[1] != StateHalfOpen || states[4] != StateClosed {
+		t.Fatal(states)
+	}
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := NewConcurrencyLimiter(1)
+	release, err := l.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Acquire(ctx); !stderrors.Is(err, errors.ConcurrencyLimitExceeded) {
+		t.Fatal("expect limit exceeded but get ", err)
+	}
+	release(nil)
+
+	l = NewConcurrencyLimiter(1, LimiterOpts.SetMaxWait(time.Second))
+	release, _ = l.Acquire(ctx)
+	time.AfterFunc(10*time.Millisecond, func() { release(nil) })
+	release, err = l.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(cctx); !stderrors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expect deadline exceeded but get ", err)
+	}
+	release(nil)
+	if l.InFlight() != 0 {
+		t.Fatal("expect 0 in flight but get ", l.InFlight())
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	c := &clock{now: time.Now()}
+	l := NewRateLimiter(10, 2)
+	l.now, l.last = c.Now, c.now
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := l.Acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := l.Acquire(ctx); !stderrors.Is(err, errors.RateLimitExceeded) {
+		t.Fatal("expect rate limit exceeded but get ", err)
+	}
+	c.now = c.now.Add(100 * time.Millisecond)
+	if _, err := l.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	l = NewRateLimiter(100, 1, LimiterOpts.SetMaxWait(time.Second))
+	l.Acquire(ctx)
+	begin := time.Now()
+	if _, err := l.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(begin) < 5*time.Millisecond {
+		t.Fatal("expect waiting for token")
+	}
+}
+
+var fail = stderrors.New("update failed")
+
+type testSession struct {
+	session.Session
+}
+
+func (s *testSession) Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	return nil, fail
+}
+
+func TestResilience(t *testing.T) {
+	sess := extensions.NewSessionEx(&testSession{Session: session.NewDummySession(0)})
+	breaker := NewBreaker(BreakerOpts.SetFailureThreshold(2))
+	limiter := NewConcurrencyLimiter(1)
+	WithResilience(sess, Opts.AddGuards(limiter), Opts.AddRule("(?i)^\\s*update", breaker))
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := sess.Execute(ctx, "UPDATE tbl SET name = ?", "a"); err != fail {
+			t.Fatal("expect update failed but get ", err)
+		}
+	}
+	if _, err := sess.Execute(ctx, "update tbl set name = ?", "a"); !stderrors.Is(err, errors.CircuitOpen) {
+		t.Fatal("expect circuit open but get ", err)
+	}
+	if _, err := sess.Query(ctx, "select * from tbl"); err != nil {
+		t.Fatal("expect query not affected but get ", err)
+	}
+	if limiter.InFlight() != 0 {
+		t.Fatal("expect limiter released but get ", limiter.InFlight())
+	}
+}
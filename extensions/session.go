@@ -17,109 +17,26 @@
 package extensions
 
 import (
-	"context"
 	"github.com/xfali/aop"
-	"github.com/xfali/lean/resultset"
 	"github.com/xfali/lean/session"
 )
 
+// SessionEx extends session.Session by aop advice.
+// Advice receives the arguments of session method with variadic params flattened,
+// e.g. Query(ctx, "select * from tbl where id = ? and name = ?", 1, "a") is [ctx, stmt, 1, "a"],
+// and params passed to Invoke are forwarded to the session as they are.
 type SessionEx struct {
-	sess  session.Session
-	proxy aop.Proxy
+	*SessionChain
 }
 
 func NewSessionEx(sess session.Session) *SessionEx {
 	ret := &SessionEx{
-		sess:  sess,
-		proxy: aop.New(sess),
+		SessionChain: NewSessionChain(sess),
 	}
 	return ret
 }
 
 func (s *SessionEx) Extend(cut aop.PointCut, advice aop.Advice) Extension {
-	s.proxy.AddAdvisor(cut, advice)
+	s.SessionChain.Extend(cut, advice)
 	return s
 }
-
-func (s *SessionEx) Ping(ctx context.Context) (ret bool) {
-	r, err := s.proxy.Call(Caller(), ctx)
-	if err != nil {
-		return false
-	}
-	if r[0] != nil {
-		ret = r[0].(bool)
-	}
-	return
-}
-
-func (s *SessionEx) Query(ctx context.Context, stmt string, params ...interface{}) (ret resultset.Result, e error) {
-	r, err := s.proxy.Call(Caller(), ctx, stmt, params)
-	if err != nil {
-		return nil, err
-	}
-	if r[0] != nil {
-		ret = r[0].(resultset.Result)
-	}
-	if r[1] != nil {
-		e = r[1].(error)
-	}
-	return
-}
-
-func (s *SessionEx) Execute(ctx context.Context, stmt string, params ...interface{}) (ret resultset.Result, e error) {
-	r, err := s.proxy.Call(Caller(), ctx, stmt, params)
-	if err != nil {
-		return nil, err
-	}
-	if r[0] != nil {
-		ret = r[0].(resultset.Result)
-	}
-	if r[1] != nil {
-		e = r[1].(error)
-	}
-	return
-}
-
-func (s *SessionEx) Begin(ctx context.Context) (e error) {
-	r, err := s.proxy.Call(Caller(), ctx)
-	if err != nil {
-		return err
-	}
-	if r[0] != nil {
-		e = r[0].(error)
-	}
-	return
-}
-
-func (s *SessionEx) Commit(ctx context.Context) (e error) {
-	r, err := s.proxy.Call(Caller(), ctx)
-	if err != nil {
-		return err
-	}
-	if r[0] != nil {
-		e = r[0].(error)
-	}
-	return
-}
-
-func (s *SessionEx) Rollback(ctx context.Context) (e error) {
-	r, err := s.proxy.Call(Caller(), ctx)
-	if err != nil {
-		return err
-	}
-	if r[0] != nil {
-		e = r[0].(error)
-	}
-	return
-}
-
-func (s *SessionEx) Close() (e error) {
-	r, err := s.proxy.Call(Caller())
-	if err != nil {
-		return err
-	}
-	if r[0] != nil {
-		e = r[0].(error)
-	}
-	return
-}
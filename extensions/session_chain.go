@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package extensions
+
+import (
+	"context"
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/session"
+)
+
+// SessionChain is a session.Session wrapped by typed interceptors without reflection.
+// Use and Extend are not concurrency safe, register interceptors before use.
+type SessionChain struct {
+	sess         session.Session
+	interceptors []Interceptor
+	funcs        chainFuncs
+}
+
+func NewSessionChain(sess session.Session, interceptors ...Interceptor) *SessionChain {
+	ret := &SessionChain{
+		sess: sess,
+	}
+	return ret.Use(interceptors...)
+}
+
+// Use appends interceptors, the interceptor registered first is called first.
+func (s *SessionChain) Use(interceptors ...Interceptor) *SessionChain {
+	s.interceptors = append(s.interceptors, interceptors...)
+	sess := s.sess
+	s.funcs = chainFuncs{
+		ping:         sess.Ping,
+		query:        sess.Query,
+		execute:      sess.Execute,
+		executeBatch: sess.ExecuteBatch,
+		begin:        sess.Begin,
+		commit: func(ctx context.Context, require bool) error {
+			return sess.Commit(ctx)
+		},
+		rollback: func(ctx context.Context, require bool) error {
+			return sess.Rollback(ctx)
+		},
+		close: func(ctx context.Context, rollback bool) error {
+			return sess.Close()
+		},
+	}.wrap(s.interceptors)
+	return s
+}
+
+// Extend adapts aop advice to Interceptor, advice receives the flattened arguments of session method.
+func (s *SessionChain) Extend(cut aop.PointCut, advice aop.Advice) Extension {
+	s.Use(adviceInterceptor(s.sess, cut, advice, true))
+	return s
+}
+
+func (s *SessionChain) Ping(ctx context.Context) bool {
+	return s.funcs.ping(ctx)
+}
+
+func (s *SessionChain) Query(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	return s.funcs.query(ctx, stmt, params...)
+}
+
+func (s *SessionChain) Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	return s.funcs.execute(ctx, stmt, params...)
+}
+
+func (s *SessionChain) ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error) {
+	return s.funcs.executeBatch(ctx, stmt, params)
+}
+
+func (s *SessionChain) Begin(ctx context.Context) error {
+	return s.funcs.begin(ctx)
+}
+
+func (s *SessionChain) Commit(ctx context.Context) error {
+	return s.funcs.commit(ctx, true)
+}
+
+func (s *SessionChain) Rollback(ctx context.Context) error {
+	return s.funcs.rollback(ctx, true)
+}
+
+func (s *SessionChain) Close() error {
+	return s.funcs.close(context.Background(), false)
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package extensions
+
+import (
+	"context"
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/statement"
+)
+
+// StatementEx extends statement.Statement by aop advice, variadic params are flattened.
+type StatementEx struct {
+	stmt     statement.Statement
+	advisors *advisors
+}
+
+func NewStatementEx(stmt statement.Statement) *StatementEx {
+	return newStatementEx(stmt, newAdvisors())
+}
+
+func newStatementEx(stmt statement.Statement, advisors *advisors) *StatementEx {
+	return &StatementEx{
+		stmt:     stmt,
+		advisors: advisors,
+	}
+}
+
+func (s *StatementEx) Extend(cut aop.PointCut, advice aop.Advice) Extension {
+	s.advisors.add(cut, advice)
+	return s
+}
+
+// Unwrap returns the statement extended.
+func (s *StatementEx) Unwrap() statement.Statement {
+	return s.stmt
+}
+
+func (s *StatementEx) Query(ctx context.Context, params ...interface{}) (resultset.Result, error) {
+	r := s.advisors.call(s.stmt, MethodQuery, append([]interface{}{ctx}, params...), func(p []interface{}) []interface{} {
+		r, err := s.stmt.Query(ctxAt(p, 0), variadicAt(p, 1)...)
+		return []interface{}{r, err}
+	})
+	return resultAt(r, 0), errorAt(r, 1)
+}
+
+func (s *StatementEx) Execute(ctx context.Context, params ...interface{}) (resultset.Result, error) {
+	r := s.advisors.call(s.stmt, MethodExecute, append([]interface{}{ctx}, params...), func(p []interface{}) []interface{} {
+		r, err := s.stmt.Execute(ctxAt(p, 0), variadicAt(p, 1)...)
+		return []interface{}{r, err}
+	})
+	return resultAt(r, 0), errorAt(r, 1)
+}
+
+func (s *StatementEx) ExecuteBatch(ctx context.Context, params [][]interface{}) (*resultset.BatchResult, error) {
+	r := s.advisors.call(s.stmt, MethodExecuteBatch, []interface{}{ctx, params}, func(p []interface{}) []interface{} {
+		batch, _ := valueAt(p, 1).([][]interface{})
+		r, err := s.stmt.ExecuteBatch(ctxAt(p, 0), batch)
+		return []interface{}{r, err}
+	})
+	v, _ := valueAt(r, 0).(*resultset.BatchResult)
+	return v, errorAt(r, 1)
+}
+
+func (s *StatementEx) Close() error {
+	r := s.advisors.call(s.stmt, MethodClose, nil, func(p []interface{}) []interface{} {
+		return []interface{}{s.stmt.Close()}
+	})
+	return errorAt(r, 0)
+}
@@ -0,0 +1,473 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tenant
+
+import (
+	"fmt"
+	"github.com/xfali/lean/errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	// words ending the table reference of FROM / UPDATE / DELETE
+	reservedWords = []string{"where", "set", "join", "inner", "left", "right", "full", "cross", "natural",
+		"straight_join", "using", "on", "group", "order", "limit", "having", "offset", "for", "union", "intersect",
+		"except", "returning", "window", "fetch", "lock"}
+	// words ending WHERE clause
+	terminatorWords = []string{"group", "order", "limit", "having", "offset", "for", "union", "intersect",
+		"except", "returning", "window", "fetch", "lock"}
+	joinWords = []string{"join", "inner", "left", "right", "full", "cross", "natural", "straight_join"}
+)
+
+// target is the statement to filter, positions are token indexes unless noted.
+type target struct {
+	insert    bool
+	table     string
+	column    string
+	qualifier string
+
+	// SELECT / UPDATE / DELETE
+	where            bool
+	condFrom, condTo int
+	// offset to add WHERE clause if where is false
+	insertAt int
+
+	// assignments of UPDATE SET and INSERT ... ON DUPLICATE KEY UPDATE
+	assignFrom, assignTo int
+
+	// INSERT, columnIndex is -1 if the tenant column is not in the column list
+	columnIndex  int
+	columnsClose int
+	tuples       [][2]int
+}
+
+type edit struct {
+	pos   int
+	text  string
+	param bool
+}
+
+// Rewrite adds the tenant predicate to SELECT / UPDATE / DELETE and the tenant column to INSERT if the statement
+// accesses the tables of filter. Statements accessing other tables are returned as they are. Tenant nil returns
+// errors.TenantNotFound. The tenant column set explicitly by INSERT or UPDATE must be the tenant, otherwise
+// errors.TenantMismatch is returned, INSERT setting it is not changed.
+//
+// Only statements with one table of filter in top level are supported, e.g. joins, subqueries and INSERT ... SELECT
+// accessing the tables return errors.TenantRewriteError. Placeholders are ? or $n.
+func (f *Filter) Rewrite(stmt string, params []interface{}, tenant interface{}) (string, []interface{}, error) {
+	toks, t, err := f.parse(stmt)
+	if err != nil || t == nil {
+		return stmt, params, err
+	}
+	if tenant == nil {
+		return "", nil, errors.TenantNotFound
+	}
+	if err := t.checkAssignments(toks, params, tenant); err != nil {
+		return "", nil, err
+	}
+	if t.insert && t.columnIndex >= 0 {
+		if err := t.checkTuples(toks, params, tenant); err != nil {
+			return "", nil, err
+		}
+		return stmt, params, nil
+	}
+
+	dollar, max := false, 0
+	for _, v := range toks {
+		if v.kind == tokenPlaceholder && v.text != "?" {
+			dollar = true
+			if n, _ := strconv.Atoi(v.text[1:]); n > max {
+				max = n
+			}
+		}
+	}
+	ph := "?"
+	if dollar {
+		ph = fmt.Sprintf("$%d", max+1)
+	}
+	var edits []edit
+	if t.insert {
+		edits = append(edits, edit{pos: toks[t.columnsClose].start, text: ", " + t.column})
+		for _, v := range t.tuples {
+			edits = append(edits, edit{pos: toks[v[1]].start, text: ", " + ph, param: true})
+		}
+	} else {
+		pred := t.column + " = " + ph
+		if t.qualifier != "" {
+			pred = t.qualifier + "." + pred
+		}
+		if t.where {
+			edits = append(edits, edit{pos: toks[t.condFrom].start, text: "("},
+				edit{pos: toks[t.condTo-1].end, text: ") AND " + pred, param: true})
+		} else {
+			edits = append(edits, edit{pos: t.insertAt, text: " WHERE " + pred, param: true})
+		}
+	}
+
+	var sb strings.Builder
+	var ret []interface{}
+	last, consumed := 0, 0
+	for _, e := range edits {
+		sb.WriteString(stmt[last:e.pos])
+		sb.WriteString(e.text)
+		last = e.pos
+		if e.param && !dollar {
+			n := 0
+			for _, v := range toks {
+				if v.kind == tokenPlaceholder && v.start < e.pos {
+					n++
+				}
+			}
+			if n > len(params) {
+				return "", nil, fmt.Errorf("%w: expect %d params but get %d ", errors.TenantRewriteError, n, len(params))
+			}
+			ret = append(ret, params[consumed:n]...)
+			ret = append(ret, tenant)
+			consumed = n
+		}
+	}
+	sb.WriteString(stmt[last:])
+	if dollar {
+		ret = append(append(ret, params...), tenant)
+	} else {
+		ret = append(ret, params[consumed:]...)
+	}
+	return sb.String(), ret, nil
+}
+
+// Check returns errors.TenantFilterMissing if the statement accesses the tables of filter without the predicate
+// "tenant column = tenant" as a top level AND condition of WHERE clause, or INSERT without the tenant column.
+// The tenant column set explicitly by INSERT or UPDATE must be the tenant, otherwise errors.TenantMismatch
+// is returned. The value of predicate and assignment must be a placeholder or a literal.
+func (f *Filter) Check(stmt string, params []interface{}, tenant interface{}) error {
+	toks, t, err := f.parse(stmt)
+	if err != nil {
+		return fmt.Errorf("%w: %v ", errors.TenantFilterMissing, err)
+	}
+	if t == nil {
+		return nil
+	}
+	if tenant == nil {
+		return errors.TenantNotFound
+	}
+	if err := t.checkAssignments(toks, params, tenant); err != nil {
+		return err
+	}
+	if t.insert {
+		if t.columnIndex < 0 {
+			return fmt.Errorf("%w: column %s not set ", errors.TenantFilterMissing, t.column)
+		}
+		return t.checkTuples(toks, params, tenant)
+	}
+	if !t.filtered(toks, params, tenant) {
+		return fmt.Errorf("%w: predicate %s = tenant not found in top level AND conditions ", errors.TenantFilterMissing, t.column)
+	}
+	return nil
+}
+
+// filtered returns true if "tenant column = tenant" is a top level AND condition of WHERE clause.
+func (t *target) filtered(toks []token, params []interface{}, tenant interface{}) bool {
+	if !t.where {
+		return false
+	}
+	for _, v := range toks[t.condFrom:t.condTo] {
+		if v.depth == 0 && (v.is("or", "xor") || v.text == "||") {
+			return false
+		}
+	}
+	start, between := t.condFrom, false
+	for i := t.condFrom; i <= t.condTo; i++ {
+		if i < t.condTo {
+			if toks[i].depth != 0 {
+				continue
+			}
+			if toks[i].is("between") {
+				between = true
+				continue
+			}
+			if !toks[i].is("and") {
+				continue
+			}
+			// AND of BETWEEN
+			if between {
+				between = false
+				continue
+			}
+		}
+		if i-start == 3 && t.matches(toks[start]) && toks[start+1].text == "=" && bound(toks, start+2, i, params, tenant) {
+			return true
+		}
+		start = i + 1
+	}
+	return false
+}
+
+// checkAssignments returns errors.TenantMismatch if the tenant column is assigned a value other than tenant.
+func (t *target) checkAssignments(toks []token, params []interface{}, tenant interface{}) error {
+	for i := t.assignFrom; i+1 < t.assignTo; i++ {
+		if toks[i].depth != 0 || !t.matches(toks[i]) || toks[i+1].text != "=" {
+			continue
+		}
+		j := i + 2
+		for j < t.assignTo && !(toks[j].depth == 0 && toks[j].text == ",") {
+			j++
+		}
+		if !bound(toks, i+2, j, params, tenant) {
+			return fmt.Errorf("%w: column %s is set to other value ", errors.TenantMismatch, t.column)
+		}
+		i = j
+	}
+	return nil
+}
+
+// checkTuples returns errors.TenantMismatch if the tenant column of any VALUES tuple is not tenant.
+func (t *target) checkTuples(toks []token, params []interface{}, tenant interface{}) error {
+	for _, v := range t.tuples {
+		pos, start := 0, v[0]+1
+		for i := v[0] + 1; i <= v[1]; i++ {
+			if i < v[1] && (toks[i].depth != toks[v[0]].depth+1 || toks[i].text != ",") {
+				continue
+			}
+			if pos == t.columnIndex {
+				if !bound(toks, start, i, params, tenant) {
+					return fmt.Errorf("%w: column %s is set to other value ", errors.TenantMismatch, t.column)
+				}
+				break
+			}
+			pos, start = pos+1, i+1
+		}
+		if pos != t.columnIndex {
+			return fmt.Errorf("%w: column %s has no value ", errors.TenantMismatch, t.column)
+		}
+	}
+	return nil
+}
+
+// matches returns true if the token is the tenant column, which may be qualified by the table or its alias.
+func (t *target) matches(v token) bool {
+	if v.kind != tokenWord {
+		return false
+	}
+	name := normalize(v.text)
+	i := strings.LastIndexByte(name, '.')
+	if name[i+1:] != t.column {
+		return false
+	}
+	if i < 0 {
+		return true
+	}
+	prefix := name[:i]
+	prefix = prefix[strings.LastIndexByte(prefix, '.')+1:]
+	return prefix == t.table || (t.qualifier != "" && prefix == normalize(t.qualifier))
+}
+
+// bound returns true if toks[from:to] is a placeholder or a literal whose value is tenant.
+func bound(toks []token, from, to int, params []interface{}, tenant interface{}) bool {
+	if to-from != 1 {
+		return false
+	}
+	v, expect := toks[from], fmt.Sprint(tenant)
+	switch v.kind {
+	case tokenPlaceholder:
+		index := -1
+		if v.text == "?" {
+			for _, p := range toks[:from+1] {
+				if p.text == "?" && p.kind == tokenPlaceholder {
+					index++
+				}
+			}
+		} else {
+			n, _ := strconv.Atoi(v.text[1:])
+			index = n - 1
+		}
+		return index >= 0 && index < len(params) && fmt.Sprint(params[index]) == expect
+	case tokenLiteral:
+		return len(v.text) >= 2 && strings.ReplaceAll(v.text[1:len(v.text)-1], "''", "'") == expect
+	case tokenWord:
+		return isDigit(v.text[0]) && v.text == expect
+	}
+	return false
+}
+
+// parse returns nil target if the statement does not access the tables of filter.
+func (f *Filter) parse(stmt string) ([]token, *target, error) {
+	toks := tokenize(stmt)
+	ref := -1
+	for i, v := range toks {
+		if v.kind != tokenWord {
+			continue
+		}
+		if _, ok := f.table(v.text); ok {
+			if ref >= 0 {
+				return nil, nil, fmt.Errorf("%w: table %s accessed more than once ", errors.TenantRewriteError, v.text)
+			}
+			ref = i
+		}
+	}
+	if ref < 0 {
+		return toks, nil, nil
+	}
+	column, _ := f.table(toks[ref].text)
+	table := normalize(toks[ref].text)
+	t := &target{
+		table:       table[strings.LastIndexByte(table, '.')+1:],
+		column:      column,
+		columnIndex: -1,
+	}
+	unsupported := fmt.Errorf("%w: table %s is not the only table of statement ", errors.TenantRewriteError, toks[ref].text)
+	if toks[ref].depth != 0 {
+		return nil, nil, unsupported
+	}
+
+	i := skip(toks, 1, "low_priority", "quick", "ignore", "only", "delayed", "high_priority")
+	switch {
+	case toks[0].is("select"):
+		if !toks[ref-1].is("from") {
+			return nil, nil, unsupported
+		}
+		next := t.alias(toks, ref)
+		if next < len(toks) && toks[next].depth == 0 && (toks[next].text == "," || toks[next].is(joinWords...)) {
+			return nil, nil, unsupported
+		}
+		t.find(toks, next)
+	case toks[0].is("update"):
+		if i != ref {
+			return nil, nil, unsupported
+		}
+		next := t.alias(toks, ref)
+		if next >= len(toks) || !toks[next].is("set") {
+			return nil, nil, unsupported
+		}
+		for _, v := range toks[next:] {
+			if v.depth == 0 && v.is("from") {
+				return nil, nil, unsupported
+			}
+		}
+		end := t.find(toks, next)
+		t.assignFrom, t.assignTo = next+1, end
+		if t.where {
+			t.assignTo = t.condFrom - 1
+		}
+	case toks[0].is("delete"):
+		if !toks[i].is("from") || i+1 != ref {
+			return nil, nil, unsupported
+		}
+		next := t.alias(toks, ref)
+		if next < len(toks) && toks[next].depth == 0 && (toks[next].text == "," || toks[next].is("using")) {
+			return nil, nil, unsupported
+		}
+		t.find(toks, next)
+	case toks[0].is("insert"):
+		if !toks[i].is("into") || i+1 != ref {
+			return nil, nil, unsupported
+		}
+		t.insert = true
+		i = ref + 1
+		if i >= len(toks) || toks[i].text != "(" {
+			return nil, nil, fmt.Errorf("%w: column list of INSERT is required ", errors.TenantRewriteError)
+		}
+		end := closing(toks, i)
+		if end < 0 {
+			return nil, nil, unsupported
+		}
+		pos := 0
+		for _, v := range toks[i+1 : end] {
+			if v.text == "," && v.depth == toks[i].depth+1 {
+				pos++
+			} else if name := normalize(v.text); v.kind == tokenWord && name[strings.LastIndexByte(name, '.')+1:] == column {
+				t.columnIndex = pos
+			}
+		}
+		t.columnsClose = end
+		i = end + 1
+		if i >= len(toks) || !toks[i].is("values", "value") {
+			return nil, nil, fmt.Errorf("%w: INSERT without VALUES ", errors.TenantRewriteError)
+		}
+		for i++; i < len(toks) && toks[i].text == "("; {
+			end = closing(toks, i)
+			if end < 0 {
+				return nil, nil, unsupported
+			}
+			t.tuples = append(t.tuples, [2]int{i, end})
+			i = end + 1
+			if i < len(toks) && toks[i].text == "," {
+				i++
+			}
+		}
+		if len(t.tuples) == 0 {
+			return nil, nil, unsupported
+		}
+		t.assignFrom, t.assignTo = i, len(toks)
+	default:
+		return nil, nil, unsupported
+	}
+	return toks, t, nil
+}
+
+// alias sets the qualifier if the table has an alias and returns the index after the table reference.
+func (t *target) alias(toks []token, ref int) int {
+	i := ref + 1
+	if i+1 < len(toks) && toks[i].is("as") && toks[i+1].kind == tokenWord {
+		t.qualifier = toks[i+1].text
+		return i + 2
+	}
+	if i < len(toks) && toks[i].kind == tokenWord && toks[i].depth == 0 && !toks[i].is(reservedWords...) {
+		t.qualifier = toks[i].text
+		return i + 1
+	}
+	return i
+}
+
+// find finds WHERE clause from index from, returns the index of the token ending the clause.
+func (t *target) find(toks []token, from int) int {
+	end := len(toks)
+	for i := from; i < len(toks); i++ {
+		if toks[i].depth == 0 && (toks[i].text == ";" || toks[i].is(terminatorWords...)) {
+			end = i
+			break
+		}
+	}
+	for i := from; i < end; i++ {
+		if toks[i].depth == 0 && toks[i].is("where") && i+1 < end {
+			t.where = true
+			t.condFrom, t.condTo = i+1, end
+			return end
+		}
+	}
+	t.insertAt = toks[end-1].end
+	return end
+}
+
+func skip(toks []token, i int, words ...string) int {
+	for i < len(toks) && toks[i].is(words...) {
+		i++
+	}
+	if i >= len(toks) {
+		return len(toks) - 1
+	}
+	return i
+}
+
+// closing returns the index of ) matching ( at index i, -1 if not found.
+func closing(toks []token, i int) int {
+	for j := i + 1; j < len(toks); j++ {
+		if toks[j].text == ")" && toks[j].depth == toks[i].depth {
+			return j
+		}
+	}
+	return -1
+}
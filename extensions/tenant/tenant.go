@@ -0,0 +1,183 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tenant
+
+import (
+	"context"
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/extensions"
+	"strings"
+)
+
+const DefaultColumn = "tenant_id"
+
+// Mode decides how Filter handles statements accessing the tables of tenant.
+type Mode int
+
+const (
+	// ModeRewrite adds the tenant predicate to statements.
+	ModeRewrite Mode = iota
+	// ModeReject returns errors.TenantFilterMissing if statements lack the predicate of the tenant, see Filter.Check.
+	ModeReject
+)
+
+type tenantKey struct{}
+
+type adminKey struct{}
+
+// WithTenant returns ctx carrying tenant, statements executed with it are filtered by the tenant.
+func WithTenant(ctx context.Context, tenant interface{}) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant set by WithTenant.
+func TenantFrom(ctx context.Context) (interface{}, bool) {
+	v := ctx.Value(tenantKey{})
+	return v, v != nil
+}
+
+// WithAdmin returns ctx bypassing the filter, e.g. for migrations and cross tenant reports.
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+// IsAdmin returns true if ctx is returned by WithAdmin.
+func IsAdmin(ctx context.Context) bool {
+	v, _ := ctx.Value(adminKey{}).(bool)
+	return v
+}
+
+type Opt func(*Filter)
+
+// Filter adds tenant predicate to the statements accessing the configured tables.
+type Filter struct {
+	column string
+	mode   Mode
+	// table name => tenant column, empty means the default column
+	tables map[string]string
+}
+
+// NewFilter creates Filter, tables are added by Opts.AddTable.
+func NewFilter(opts ...Opt) *Filter {
+	ret := &Filter{
+		column: DefaultColumn,
+		tables: map[string]string{},
+	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
+}
+
+// table returns the tenant column of table, the schema of table is ignored if it is not configured.
+func (f *Filter) table(name string) (string, bool) {
+	name = normalize(name)
+	column, ok := f.tables[name]
+	if !ok {
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return "", false
+		}
+		if column, ok = f.tables[name[i+1:]]; !ok {
+			return "", false
+		}
+	}
+	if column == "" {
+		column = f.column
+	}
+	return column, true
+}
+
+// NewAdvice creates advice which filters Query, Execute and ExecuteBatch by the tenant of ctx.
+// Statements with admin ctx are not filtered.
+func NewAdvice(filter *Filter) aop.Advice {
+	return filter.advice
+}
+
+// WithFilter extends the Connection / Session / Executor with tenant filter advice.
+func WithFilter(ext extensions.Extension, filter *Filter) extensions.Extension {
+	return ext.Extend(extensions.PointCutStatement(), NewAdvice(filter))
+}
+
+func (f *Filter) advice(invocation aop.Invocation, params []interface{}) []interface{} {
+	call := extensions.ParseCall(invocation.MethodName(), params)
+	if !call.IsStatement() || IsAdmin(call.Ctx) {
+		return invocation.Invoke(params)
+	}
+	tenant, _ := TenantFrom(call.Ctx)
+	if f.mode == ModeReject {
+		var err error
+		if call.Method == extensions.MethodExecuteBatch {
+			for _, v := range call.BatchParams {
+				if err = f.Check(call.Stmt, v, tenant); err != nil {
+					break
+				}
+			}
+		} else {
+			err = f.Check(call.Stmt, call.Params, tenant)
+		}
+		if err != nil {
+			return []interface{}{nil, err}
+		}
+		return invocation.Invoke(params)
+	}
+
+	if call.Method == extensions.MethodExecuteBatch {
+		if len(call.BatchParams) == 0 {
+			return invocation.Invoke(params)
+		}
+		stmt := call.Stmt
+		batch := make([][]interface{}, len(call.BatchParams))
+		for i, v := range call.BatchParams {
+			s, p, err := f.Rewrite(call.Stmt, v, tenant)
+			if err != nil {
+				return []interface{}{nil, err}
+			}
+			stmt, batch[i] = s, p
+		}
+		return invocation.Invoke([]interface{}{params[0], stmt, batch})
+	}
+	stmt, p, err := f.Rewrite(call.Stmt, call.Params, tenant)
+	if err != nil {
+		return []interface{}{nil, err}
+	}
+	return invocation.Invoke(append([]interface{}{params[0], stmt}, p...))
+}
+
+type opts struct{}
+
+var Opts opts
+
+// AddTable filters table by column, empty column means the default column. Table may be qualified by schema.
+func (opts) AddTable(table string, column string) Opt {
+	return func(f *Filter) {
+		f.tables[normalize(table)] = normalize(column)
+	}
+}
+
+// SetDefaultColumn sets the default tenant column, default is DefaultColumn.
+func (opts) SetDefaultColumn(column string) Opt {
+	return func(f *Filter) {
+		f.column = normalize(column)
+	}
+}
+
+// SetMode sets the mode of filter, default is ModeRewrite.
+func (opts) SetMode(mode Mode) Opt {
+	return func(f *Filter) {
+		f.mode = mode
+	}
+}
@@ -0,0 +1,209 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tenant
+
+import (
+	"context"
+	stderrors "errors"
+	"github.com/xfali/lean/errors"
+	"github.com/xfali/lean/extensions"
+	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/session"
+	"reflect"
+	"testing"
+)
+
+type testSession struct {
+	session.Session
+	stmt   string
+	params []interface{}
+	batch  [][]interface{}
+}
+
+func (s *testSession) Query(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	s.stmt, s.params = stmt, params
+	return nil, nil
+}
+
+func (s *testSession) Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	s.stmt, s.params = stmt, params
+	return nil, nil
+}
+
+func (s *testSession) ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error) {
+	s.stmt, s.batch = stmt, params
+	return nil, nil
+}
+
+func newTestFilter(opts ...Opt) *Filter {
+	return NewFilter(append([]Opt{Opts.AddTable("users", ""), Opts.AddTable("orders", "org_id")}, opts...)...)
+}
+
+func TestRewrite(t *testing.T) {
+	f := newTestFilter()
+	for _, v := range []struct {
+		stmt   string
+		params []interface{}
+		expect string
+		ret    []interface{}
+	}{
+		{"select * from users", nil,
+			"select * from users WHERE tenant_id = ?", []interface{}{"t1"}},
+		{"select * from users u where u.id = ? or u.name = ? order by id limit 10", []interface{}{1, "a"},
+			"select * from users u where (u.id = ? or u.name = ?) AND u.tenant_id = ? order by id limit 10", []interface{}{1, "a", "t1"}},
+		{"select count(*) from `users` as u group by u.age", nil,
+			"select count(*) from `users` as u WHERE u.tenant_id = ? group by u.age", []interface{}{"t1"}},
+		{"select * from app.orders where id in (select id from tmp where name = ?) limit ?", []interface{}{"a", 10},
+			"select * from app.orders where (id in (select id from tmp where name = ?)) AND org_id = ? limit ?", []interface{}{"a", "t1", 10}},
+		{"update users set name = ? where id = ?", []interface{}{"a", 1},
+			"update users set name = ? where (id = ?) AND tenant_id = ?", []interface{}{"a", 1, "t1"}},
+		{"update orders set name = $1 where id = $2", []interface{}{"a", 1},
+			"update orders set name = $1 where (id = $2) AND org_id = $3", []interface{}{"a", 1, "t1"}},
+		{"delete from users;", nil,
+			"delete from users WHERE tenant_id = ?;", []interface{}{"t1"}},
+		{"insert into users (id, name) values (?, ?), (?, 'a;b')", []interface{}{1, "a", 2},
+			"insert into users (id, name, tenant_id) values (?, ?, ?), (?, 'a;b', ?)", []interface{}{1, "a", "t1", 2, "t1"}},
+		{"insert into users (id, tenant_id) values (?, ?), (?, 't1')", []interface{}{1, "t1", 2},
+			"insert into users (id, tenant_id) values (?, ?), (?, 't1')", []interface{}{1, "t1", 2}},
+		{"select * from users where name = 'x' and age > ?", []interface{}{1},
+			"select * from users where (name = 'x' and age > ?) AND tenant_id = ?", []interface{}{1, "t1"}},
+		{"select * from other where name = 'users'", nil,
+			"select * from other where name = 'users'", nil},
+		{"select * from orders # all rows", nil,
+			"select * from orders WHERE org_id = ? # all rows", []interface{}{"t1"}},
+		{"select * from orders where id = ? # by id", []interface{}{1},
+			"select * from orders where (id = ?) AND org_id = ? # by id", []interface{}{1, "t1"}},
+		{"select * from orders where id = ? -- by id\n/* end */", []interface{}{1},
+			"select * from orders where (id = ?) AND org_id = ? -- by id\n/* end */", []interface{}{1, "t1"}},
+	} {
+		stmt, params, err := f.Rewrite(v.stmt, v.params, "t1")
+		if err != nil {
+			t.Fatal(v.stmt, err)
+		}
+		if stmt != v.expect {
+			t.Fatal("expect ", v.expect, " but get ", stmt)
+		}
+		if !reflect.DeepEqual(params, v.ret) {
+			t.Fatal("expect ", v.ret, " but get ", params)
+		}
+	}
+
+	for _, stmt := range []string{
+		"select * from users u join orders o on u.id = o.uid",
+		"select * from users, other",
+		"select * from other where id in (select id from users)",
+		"insert into users values (?, ?)",
+		"insert into users (id) select id from other",
+		"update users u, other o set u.name = o.name",
+	} {
+		if _, _, err := f.Rewrite(stmt, nil, "t1"); !stderrors.Is(err, errors.TenantRewriteError) {
+			t.Fatal("expect rewrite error of ", stmt, " but get ", err)
+		}
+	}
+	if _, _, err := f.Rewrite("select * from users", nil, nil); !stderrors.Is(err, errors.TenantNotFound) {
+		t.Fatal("expect tenant not found but get ", err)
+	}
+
+	for stmt, params := range map[string][]interface{}{
+		"insert into users (id, tenant_id) values (?, ?)":                            {1, "t2"},
+		"insert into users (id, tenant_id) values (?, ?), (?, 't2')":                 {1, "t1", 2},
+		"insert into users (id, tenant_id) values (?, lower(?))":                     {1, "t1"},
+		"update users set name = ?, tenant_id = ? where id = ?":                      {"a", "t2", 1},
+		"update orders o set o.org_id = $1 where id = $2":                            {"t2", 1},
+		"insert into users (id) values (?) on duplicate key update tenant_id = 't2'": {1},
+	} {
+		if _, _, err := f.Rewrite(stmt, params, "t1"); !stderrors.Is(err, errors.TenantMismatch) {
+			t.Fatal("expect tenant mismatch of ", stmt, " but get ", err)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	f := newTestFilter()
+	for _, v := range []struct {
+		stmt   string
+		params []interface{}
+		expect error
+	}{
+		{"select * from users where tenant_id = ? and id = ?", []interface{}{"t1", 1}, nil},
+		{"select * from users u where u.id between ? and ? and u.tenant_id = 't1'", []interface{}{1, 2}, nil},
+		{"delete from orders where (id = $1 or id = $2) and org_id=$3", []interface{}{1, 2, "t1"}, nil},
+		{"update users set tenant_id = ?, name = ? where tenant_id = ?", []interface{}{"t1", "a", "t1"}, nil},
+		{"insert into users (id, tenant_id) values (?, ?)", []interface{}{1, "t1"}, nil},
+		{"select * from other", nil, nil},
+		{"select * from orders where org_id = ? # by tenant", []interface{}{"t1"}, nil},
+		{"select * from orders # all rows", nil, errors.TenantFilterMissing},
+		{"select * from orders where id = ? # by id", []interface{}{1}, errors.TenantFilterMissing},
+		{"select * from orders where id = ? # and org_id = ?", []interface{}{1, "t1"}, errors.TenantFilterMissing},
+		{"select * from users where id = ?", []interface{}{1}, errors.TenantFilterMissing},
+		{"select * from users where id = ? or tenant_id = ?", []interface{}{1, "t1"}, errors.TenantFilterMissing},
+		{"select * from users where tenant_id = ? || id = ?", []interface{}{"t1", 1}, errors.TenantFilterMissing},
+		{"select * from users where tenant_id = ?", []interface{}{"t2"}, errors.TenantFilterMissing},
+		{"select * from users where tenant_id in (?, ?)", []interface{}{"t1", "t2"}, errors.TenantFilterMissing},
+		{"select * from users where (tenant_id = ? or 1 = 1)", []interface{}{"t1"}, errors.TenantFilterMissing},
+		{"select * from users where my_tenant_id = ?", []interface{}{"t1"}, errors.TenantFilterMissing},
+		{"update orders set org_id = ?", []interface{}{"t1"}, errors.TenantFilterMissing},
+		{"update users set tenant_id = ? where tenant_id = ?", []interface{}{"t2", "t1"}, errors.TenantMismatch},
+		{"insert into users (id) values (?)", []interface{}{1}, errors.TenantFilterMissing},
+		{"insert into users (id, tenant_id) values (?, ?)", []interface{}{1, "t2"}, errors.TenantMismatch},
+		{"select * from users u join orders o on u.id = o.user_id", nil, errors.TenantFilterMissing},
+	} {
+		err := f.Check(v.stmt, v.params, "t1")
+		if v.expect == nil && err != nil || v.expect != nil && !stderrors.Is(err, v.expect) {
+			t.Fatal(v.stmt, " expect ", v.expect, " but get ", err)
+		}
+	}
+	if err := f.Check("select * from users where tenant_id = ?", []interface{}{"t1"}, nil); !stderrors.Is(err, errors.TenantNotFound) {
+		t.Fatal("expect tenant not found but get ", err)
+	}
+}
+
+func TestFilterAdvice(t *testing.T) {
+	ts := &testSession{}
+	sess := extensions.NewSessionEx(ts)
+	WithFilter(sess, newTestFilter())
+
+	ctx := WithTenant(context.Background(), "t1")
+	sess.Query(ctx, "select * from users where id = ?", 1)
+	if ts.stmt != "select * from users where (id = ?) AND tenant_id = ?" || !reflect.DeepEqual(ts.params, []interface{}{1, "t1"}) {
+		t.Fatal("unexpected query ", ts.stmt, ts.params)
+	}
+	sess.ExecuteBatch(ctx, "insert into orders (id) values (?)", [][]interface{}{{1}, {2}})
+	if ts.stmt != "insert into orders (id, org_id) values (?, ?)" || !reflect.DeepEqual(ts.batch, [][]interface{}{{1, "t1"}, {2, "t1"}}) {
+		t.Fatal("unexpected batch ", ts.stmt, ts.batch)
+	}
+	sess.Execute(WithAdmin(ctx), "delete from users")
+	if ts.stmt != "delete from users" {
+		t.Fatal("expect admin bypass but get ", ts.stmt)
+	}
+	if _, err := sess.Execute(context.Background(), "delete from users"); !stderrors.Is(err, errors.TenantNotFound) {
+		t.Fatal("expect tenant not found but get ", err)
+	}
+
+	ts = &testSession{}
+	sess = extensions.NewSessionEx(ts)
+	WithFilter(sess, newTestFilter(Opts.SetMode(ModeReject)))
+	if _, err := sess.Query(ctx, "select * from users"); !stderrors.Is(err, errors.TenantFilterMissing) {
+		t.Fatal("expect filter missing but get ", err)
+	}
+	if ts.stmt != "" {
+		t.Fatal("expect rejected but get ", ts.stmt)
+	}
+	sess.Query(ctx, "select * from users where tenant_id = ?", "t1")
+	if ts.stmt != "select * from users where tenant_id = ?" {
+		t.Fatal("expect statement unchanged but get ", ts.stmt)
+	}
+}
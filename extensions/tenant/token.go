@@ -0,0 +1,159 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tenant
+
+import (
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenPlaceholder
+	tokenPunct
+	tokenLiteral
+)
+
+// token of statement, comments (--, # and /* */) are skipped. Parentheses have the depth outside them.
+type token struct {
+	kind       tokenKind
+	text       string
+	start, end int
+	depth      int
+}
+
+func (t token) is(words ...string) bool {
+	if t.kind != tokenWord {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenize(s string) []token {
+	var ret []token
+	depth := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\'':
+			j := i + 1
+			for j < len(s) {
+				if s[j] == '\\' {
+					j += 2
+					continue
+				}
+				if s[j] == '\'' {
+					if j+1 < len(s) && s[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j >= len(s) {
+				j = len(s) - 1
+			}
+			ret = append(ret, token{kind: tokenLiteral, text: s[i : j+1], start: i, end: j + 1, depth: depth})
+			i = j + 1
+		case c == '#' || c == '-' && i+1 < len(s) && s[i+1] == '-':
+			j := strings.IndexByte(s[i:], '\n')
+			if j < 0 {
+				i = len(s)
+			} else {
+				i += j + 1
+			}
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			j := strings.Index(s[i+2:], "*/")
+			if j < 0 {
+				i = len(s)
+			} else {
+				i += j + 4
+			}
+		case strings.IndexByte("=<>!+-*/%|&^~:", c) >= 0:
+			n := 1
+			if i+1 < len(s) {
+				switch s[i : i+2] {
+				case "<=", ">=", "<>", "!=", "==", "||", "&&", "::", "<<", ">>":
+					n = 2
+				}
+			}
+			ret = append(ret, token{kind: tokenPunct, text: s[i : i+n], start: i, end: i + n, depth: depth})
+			i += n
+		case c == '(':
+			ret = append(ret, token{kind: tokenPunct, text: "(", start: i, end: i + 1, depth: depth})
+			depth++
+			i++
+		case c == ')':
+			depth--
+			ret = append(ret, token{kind: tokenPunct, text: ")", start: i, end: i + 1, depth: depth})
+			i++
+		case c == ',' || c == ';':
+			ret = append(ret, token{kind: tokenPunct, text: s[i : i+1], start: i, end: i + 1, depth: depth})
+			i++
+		case c == '?':
+			ret = append(ret, token{kind: tokenPlaceholder, text: "?", start: i, end: i + 1, depth: depth})
+			i++
+		case c == '$' && i+1 < len(s) && isDigit(s[i+1]):
+			j := i + 1
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			ret = append(ret, token{kind: tokenPlaceholder, text: s[i:j], start: i, end: j, depth: depth})
+			i = j
+		case isWordChar(c) || c == '"' || c == '`':
+			j := i
+			for j < len(s) {
+				if s[j] == '"' || s[j] == '`' {
+					k := strings.IndexByte(s[j+1:], s[j])
+					if k < 0 {
+						j = len(s)
+						break
+					}
+					j += k + 2
+				} else if isWordChar(s[j]) || s[j] == '.' {
+					j++
+				} else {
+					break
+				}
+			}
+			ret = append(ret, token{kind: tokenWord, text: s[i:j], start: i, end: j, depth: depth})
+			i = j
+		default:
+			i++
+		}
+	}
+	return ret
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// normalize strips quotes of identifier and converts it to lower case.
+func normalize(ident string) string {
+	return strings.ToLower(strings.NewReplacer("`", "", `"`, "").Replace(ident))
+}
@@ -0,0 +1,208 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/extensions"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"sync"
+)
+
+const (
+	InstrumentationName = "github.com/xfali/lean"
+
+	RowsAffectedKey = attribute.Key("db.rows_affected")
+	BatchSizeKey    = attribute.Key("db.batch_size")
+	MethodKey       = attribute.Key("lean.method")
+
+	TransactionSpanName = "TRANSACTION"
+)
+
+type Opt func(*tracer)
+
+type tracer struct {
+	provider   trace.TracerProvider
+	tracer     trace.Tracer
+	system     string
+	statement  bool
+	attributes []attribute.KeyValue
+
+	// transaction spans by session
+	locker  sync.Mutex
+	txSpans map[interface{}]trace.Span
+}
+
+// NewAdvice creates advice which starts spans of Query, Execute, ExecuteBatch, Begin, Commit and Rollback from
+// the context of call. Begin starts a transaction span which ends with Commit, Rollback or Close, spans of the
+// statements in transaction are its children. Transaction spans are kept by session, so the advice can be shared
+// by the sessions of ConnectionEx.
+func NewAdvice(opts ...Opt) aop.Advice {
+	t := &tracer{
+		system:    semconv.DBSystemOtherSQL.Value.AsString(),
+		statement: true,
+		txSpans:   map[interface{}]trace.Span{},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.provider == nil {
+		t.provider = otel.GetTracerProvider()
+	}
+	t.tracer = t.provider.Tracer(InstrumentationName)
+	return t.advice
+}
+
+// WithTracing extends the Connection / Session / Executor with tracing advice, Close is advised to end the
+// transaction span of the session which is closed without Commit or Rollback.
+func WithTracing(ext extensions.Extension, opts ...Opt) extensions.Extension {
+	return ext.Extend(extensions.PointCutMethods(extensions.MethodQuery, extensions.MethodExecute,
+		extensions.MethodExecuteBatch, extensions.MethodBegin, extensions.MethodCommit, extensions.MethodRollback,
+		extensions.MethodClose), NewAdvice(opts...))
+}
+
+func (t *tracer) advice(invocation aop.Invocation, params []interface{}) []interface{} {
+	call := extensions.ParseCall(invocation.MethodName(), params)
+	target := extensions.InvocationTarget(invocation)
+	if call.Method == extensions.MethodClose {
+		ret := invocation.Invoke(params)
+		t.end(target, call.Method, nil)
+		return ret
+	}
+	ctx := t.parent(target, call)
+	if call.Method == extensions.MethodBegin {
+		ctx = t.begin(target, ctx)
+	}
+
+	attrs := append([]attribute.KeyValue{
+		semconv.DBSystemKey.String(t.system),
+		MethodKey.String(call.Method),
+	}, t.attributes...)
+	name := call.Operation()
+	attrs = append(attrs, semconv.DBOperationKey.String(name))
+	if call.Stmt != "" && t.statement {
+		attrs = append(attrs, semconv.DBStatementKey.String(call.Stmt))
+	}
+	if call.Method == extensions.MethodExecuteBatch {
+		attrs = append(attrs, BatchSizeKey.Int(len(call.BatchParams)))
+	}
+
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	if len(params) > 0 {
+		if _, ok := params[0].(context.Context); ok {
+			params[0] = ctx
+		}
+	}
+	ret := invocation.Invoke(params)
+
+	outcome := extensions.ParseOutcome(ret)
+	if n := outcome.RowsAffected(call.Method); n >= 0 {
+		span.SetAttributes(RowsAffectedKey.Int64(n))
+	}
+	if outcome.Err != nil {
+		span.RecordError(outcome.Err)
+		span.SetStatus(codes.Error, outcome.Err.Error())
+	}
+	span.End()
+
+	switch call.Method {
+	case extensions.MethodBegin:
+		if outcome.Err != nil {
+			t.end(target, call.Method, outcome.Err)
+		}
+	case extensions.MethodCommit, extensions.MethodRollback:
+		t.end(target, call.Method, outcome.Err)
+	}
+	return ret
+}
+
+// parent returns the context of call with the transaction span of session if transaction has begun, so that the
+// deadline and values of the context are kept.
+func (t *tracer) parent(target interface{}, call extensions.Call) context.Context {
+	t.locker.Lock()
+	defer t.locker.Unlock()
+	if span, ok := t.txSpans[target]; ok && call.Method != extensions.MethodBegin {
+		return trace.ContextWithSpan(call.Ctx, span)
+	}
+	return call.Ctx
+}
+
+func (t *tracer) begin(target interface{}, ctx context.Context) context.Context {
+	t.locker.Lock()
+	defer t.locker.Unlock()
+	if span, ok := t.txSpans[target]; ok {
+		return trace.ContextWithSpan(ctx, span)
+	}
+	ctx, span := t.tracer.Start(ctx, TransactionSpanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append([]attribute.KeyValue{semconv.DBSystemKey.String(t.system)}, t.attributes...)...))
+	t.txSpans[target] = span
+	return ctx
+}
+
+func (t *tracer) end(target interface{}, method string, err error) {
+	t.locker.Lock()
+	span, ok := t.txSpans[target]
+	delete(t.txSpans, target)
+	t.locker.Unlock()
+	if !ok {
+		return
+	}
+	span.SetAttributes(attribute.String("db.transaction.outcome", strings.ToLower(method)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+type opts struct{}
+
+var Opts opts
+
+func (opts) SetTracerProvider(provider trace.TracerProvider) Opt {
+	return func(t *tracer) {
+		t.provider = provider
+	}
+}
+
+// SetSystem sets db.system, e.g. mysql, postgresql or nebula, default is other_sql.
+func (opts) SetSystem(system string) Opt {
+	return func(t *tracer) {
+		t.system = system
+	}
+}
+
+// SetStatementEnabled records db.statement, default is true.
+func (opts) SetStatementEnabled(enable bool) Opt {
+	return func(t *tracer) {
+		t.statement = enable
+	}
+}
+
+// AddAttributes adds attributes to all spans, e.g. db.name.
+func (opts) AddAttributes(attrs ...attribute.KeyValue) Opt {
+	return func(t *tracer) {
+		t.attributes = append(t.attributes, attrs...)
+	}
+}
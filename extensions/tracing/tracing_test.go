@@ -0,0 +1,138 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/xfali/lean/extensions"
+	"github.com/xfali/lean/resultset"
+	"github.com/xfali/lean/session"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"testing"
+	"time"
+)
+
+type testSession struct {
+	session.Session
+	ctx context.Context
+}
+
+func (s *testSession) Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+	s.ctx = ctx
+	return nil, errors.New("exec failed")
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	sess := extensions.NewSessionEx(&testSession{Session: session.NewDummySession(0)})
+	WithTracing(sess, Opts.SetTracerProvider(provider), Opts.SetSystem("mysql"))
+
+	ctx, root := provider.Tracer("test").Start(context.Background(), "root")
+	sess.Query(ctx, "select * from tbl where id = ?", 1)
+	sess.Begin(ctx)
+	sess.Query(ctx, "SELECT name FROM tbl")
+	sess.Execute(ctx, "update tbl set name = ?", "x")
+	sess.Commit(ctx)
+	root.End()
+
+	spans := exporter.GetSpans()
+	byName := map[string]tracetest.SpanStub{}
+	for _, s := range spans {
+		t.Log(s.Name, s.Parent.SpanID(), s.SpanContext.SpanID())
+		if _, ok := byName[s.Name]; !ok {
+			byName[s.Name] = s
+		}
+	}
+	if len(spans) != 7 {
+		t.Fatal("expect 7 spans but get ", len(spans))
+	}
+	rootID := byName["root"].SpanContext.SpanID()
+	tx := byName[TransactionSpanName]
+	if tx.Parent.SpanID() != rootID {
+		t.Fatal("expect transaction span child of root")
+	}
+	for _, name := range []string{"BEGIN", "UPDATE", "COMMIT"} {
+		if byName[name].Parent.SpanID() != tx.SpanContext.SpanID() {
+			t.Fatal("expect span in transaction ", name)
+		}
+	}
+	if spans[0].Name != "SELECT" || spans[0].Parent.SpanID() != rootID {
+		t.Fatal("expect first select child of root ", spans[0].Name)
+	}
+
+	attrs := map[string]string{}
+	for _, a := range spans[0].Attributes {
+		attrs[string(a.Key)] = a.Value.Emit()
+	}
+	if attrs[string(semconv.DBSystemKey)] != "mysql" || attrs[string(semconv.DBOperationKey)] != "SELECT" ||
+		attrs[string(semconv.DBStatementKey)] != "select * from tbl where id = ?" {
+		t.Fatal("unexpected attributes ", attrs)
+	}
+
+	update := byName["UPDATE"]
+	if update.Status.Code != codes.Error || len(update.Events) == 0 {
+		t.Fatal("expect error recorded ", update.Status)
+	}
+}
+
+type valueKey struct{}
+
+func TestTracingSessions(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	advice := NewAdvice(Opts.SetTracerProvider(provider))
+	cut := extensions.PointCutMethods(extensions.MethodExecute, extensions.MethodBegin, extensions.MethodCommit,
+		extensions.MethodRollback, extensions.MethodClose)
+	ts1 := &testSession{Session: session.NewDummySession(0)}
+	sess1 := extensions.NewSessionEx(ts1)
+	sess1.Extend(cut, advice)
+	sess2 := extensions.NewSessionEx(&testSession{Session: session.NewDummySession(0)})
+	sess2.Extend(cut, advice)
+
+	sess1.Begin(context.Background())
+	sess2.Begin(context.Background())
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), valueKey{}, "v"), time.Minute)
+	defer cancel()
+	sess1.Execute(ctx, "update tbl set name = ?", "x")
+	if _, ok := ts1.ctx.Deadline(); !ok || ts1.ctx.Value(valueKey{}) != "v" {
+		t.Fatal("expect ctx of caller kept in transaction")
+	}
+	sess2.Commit(context.Background())
+	sess1.Close()
+
+	var txs []tracetest.SpanStub
+	var update tracetest.SpanStub
+	for _, s := range exporter.GetSpans() {
+		switch s.Name {
+		case TransactionSpanName:
+			txs = append(txs, s)
+		case "UPDATE":
+			update = s
+		}
+	}
+	if len(txs) != 2 {
+		t.Fatal("expect transaction span of each session ended but get ", len(txs))
+	}
+	if update.Parent.SpanID() != txs[1].SpanContext.SpanID() {
+		t.Fatal("expect update in transaction of session 1")
+	}
+}
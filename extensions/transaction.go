@@ -0,0 +1,105 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package extensions
+
+import (
+	"context"
+	"github.com/xfali/aop"
+	"github.com/xfali/lean/handler"
+	"github.com/xfali/lean/transaction"
+)
+
+// TransactionEx extends transaction.Transaction by aop advice, handlers got from it and the handlers passed to
+// callbacks are extended by the same advice.
+type TransactionEx struct {
+	tx       transaction.Transaction
+	advisors *advisors
+}
+
+func NewTransactionEx(tx transaction.Transaction) *TransactionEx {
+	return newTransactionEx(tx, newAdvisors())
+}
+
+func newTransactionEx(tx transaction.Transaction, advisors *advisors) *TransactionEx {
+	return &TransactionEx{
+		tx:       tx,
+		advisors: advisors,
+	}
+}
+
+func (t *TransactionEx) Extend(cut aop.PointCut, advice aop.Advice) Extension {
+	t.advisors.add(cut, advice)
+	return t
+}
+
+func (t *TransactionEx) Close() error {
+	r := t.advisors.call(t.tx, MethodClose, nil, func(p []interface{}) []interface{} {
+		return []interface{}{t.tx.Close()}
+	})
+	return errorAt(r, 0)
+}
+
+func (t *TransactionEx) Ping(ctx context.Context) bool {
+	r := t.advisors.call(t.tx, MethodPing, []interface{}{ctx}, func(p []interface{}) []interface{} {
+		return []interface{}{t.tx.Ping(ctxAt(p, 0))}
+	})
+	v, _ := valueAt(r, 0).(bool)
+	return v
+}
+
+func (t *TransactionEx) GetHandler() handler.Handler {
+	r := t.advisors.call(t.tx, MethodGetHandler, nil, func(p []interface{}) []interface{} {
+		return []interface{}{t.tx.GetHandler()}
+	})
+	return t.handler(valueAt(r, 0))
+}
+
+func (t *TransactionEx) Begin(ctx context.Context, successCallback func(handler.Handler) error) error {
+	return t.end(MethodBegin, t.tx.Begin, ctx, successCallback)
+}
+
+func (t *TransactionEx) Commit(ctx context.Context, successCallback func(handler.Handler) error) error {
+	return t.end(MethodCommit, t.tx.Commit, ctx, successCallback)
+}
+
+func (t *TransactionEx) Rollback(ctx context.Context, successCallback func(handler.Handler) error) error {
+	return t.end(MethodRollback, t.tx.Rollback, ctx, successCallback)
+}
+
+type txFunc func(ctx context.Context, successCallback func(handler.Handler) error) error
+
+func (t *TransactionEx) end(method string, f txFunc, ctx context.Context, successCallback func(handler.Handler) error) error {
+	r := t.advisors.call(t.tx, method, []interface{}{ctx, successCallback}, func(p []interface{}) []interface{} {
+		cb, _ := valueAt(p, 1).(func(handler.Handler) error)
+		if cb != nil {
+			callback := cb
+			cb = func(h handler.Handler) error {
+				return callback(t.handler(h))
+			}
+		}
+		return []interface{}{f(ctxAt(p, 0), cb)}
+	})
+	return errorAt(r, 0)
+}
+
+func (t *TransactionEx) handler(v interface{}) handler.Handler {
+	h, _ := v.(handler.Handler)
+	switch h.(type) {
+	case nil, HandlerEx, TxHandlerEx:
+		return h
+	}
+	return newHandlerEx(h, t.advisors)
+}
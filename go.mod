@@ -3,9 +3,28 @@ module github.com/xfali/lean
 go 1.18
 
 require (
+	github.com/prometheus/client_golang v1.14.0
+	github.com/vesoft-inc/nebula-go/v3 v3.4.0-1
+	github.com/xfali/aop v0.0.0-20230117133031-83f64b50312b
+	github.com/xfali/reflection v0.0.0-20230406143950-299589bbddbe
+	github.com/xfali/xlog v0.1.6
+	go.opentelemetry.io/otel v1.14.0
+	go.opentelemetry.io/otel/sdk v1.14.0
+	go.opentelemetry.io/otel/trace v1.14.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.1.2 // indirect
 	github.com/facebook/fbthrift v0.31.1-0.20211129061412-801ed7f9f295 // indirect
-	github.com/vesoft-inc/nebula-go/v3 v3.4.0-1 // indirect
-	github.com/xfali/aop v0.0.0-20230117133031-83f64b50312b // indirect
-	github.com/xfali/reflection v0.0.0-20230406143950-299589bbddbe // indirect
-	github.com/xfali/xlog v0.1.6 // indirect
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/prometheus/client_model v0.3.0 // indirect
+	github.com/prometheus/common v0.37.0 // indirect
+	github.com/prometheus/procfs v0.8.0 // indirect
+	golang.org/x/sys v0.5.0 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
 )
@@ -29,3 +29,13 @@ type Handler interface {
 
 	Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error)
 }
+
+// TxHandler is implemented by handlers bound to a transaction. Statements prepared by the Parent handler
+// can be bound to the transaction instead of being prepared again.
+type TxHandler interface {
+	Handler
+
+	Parent() Handler
+
+	Bind(ctx context.Context, stmt statement.Statement) (statement.Statement, error)
+}
@@ -0,0 +1,261 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"container/list"
+	"github.com/xfali/lean/statement"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultLRUPoolCapacity = 256
+)
+
+type PoolStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Size      int
+}
+
+type LRUPoolOpt func(*lruPool)
+
+type lruKey struct {
+	h   Handler
+	sql string
+}
+
+type lruEntry struct {
+	key      lruKey
+	stmt     statement.Statement
+	accessed time.Time
+	refs     int
+	removed  bool
+}
+
+type lruPool struct {
+	capacity int
+	ttl      time.Duration
+
+	locker   sync.Mutex
+	lru      *list.List
+	elements map[Handler]map[string]*list.Element
+
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+// NewLRUPool creates a SharedStatementPool holding at most capacity statements. The least recently used statement
+// is evicted and closed when the pool is full, statements not used for ttl are evicted as well if ttl is set.
+func NewLRUPool(capacity int, opts ...LRUPoolOpt) *lruPool {
+	if capacity <= 0 {
+		capacity = DefaultLRUPoolCapacity
+	}
+	ret := &lruPool{
+		capacity: capacity,
+		lru:      list.New(),
+		elements: map[Handler]map[string]*list.Element{},
+	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
+}
+
+func (p *lruPool) Get(h Handler, sql string) (statement.Statement, bool) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	if entry := p.get(h, sql, true); entry != nil {
+		return entry.stmt, true
+	}
+	return nil, false
+}
+
+func (p *lruPool) Acquire(h Handler, sql string) (statement.Statement, func(), bool) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	if entry := p.get(h, sql, true); entry != nil {
+		return entry.stmt, p.acquire(entry), true
+	}
+	return nil, nil, false
+}
+
+func (p *lruPool) Put(h Handler, sql string, stmt statement.Statement) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	if e, ok := p.elements[h][sql]; ok {
+		entry := e.Value.(*lruEntry)
+		if entry.stmt != stmt {
+			p.remove(e)
+		} else {
+			entry.accessed = time.Now()
+			p.lru.MoveToFront(e)
+			return
+		}
+	}
+	p.put(h, sql, stmt)
+}
+
+func (p *lruPool) PutAndAcquire(h Handler, sql string, stmt statement.Statement) (statement.Statement, func()) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	if entry := p.get(h, sql, false); entry != nil {
+		if entry.stmt != stmt {
+			_ = stmt.Close()
+		}
+		return entry.stmt, p.acquire(entry)
+	}
+	return stmt, p.acquire(p.put(h, sql, stmt))
+}
+
+func (p *lruPool) get(h Handler, sql string, stats bool) *lruEntry {
+	if e, ok := p.elements[h][sql]; ok {
+		entry := e.Value.(*lruEntry)
+		now := time.Now()
+		if p.ttl > 0 && now.Sub(entry.accessed) > p.ttl {
+			p.remove(e)
+			atomic.AddInt64(&p.evictions, 1)
+		} else {
+			entry.accessed = now
+			p.lru.MoveToFront(e)
+			if stats {
+				atomic.AddInt64(&p.hits, 1)
+			}
+			return entry
+		}
+	}
+	if stats {
+		atomic.AddInt64(&p.misses, 1)
+	}
+	return nil
+}
+
+func (p *lruPool) put(h Handler, sql string, stmt statement.Statement) *lruEntry {
+	now := time.Now()
+	v, ok := p.elements[h]
+	if !ok {
+		v = map[string]*list.Element{}
+		p.elements[h] = v
+	}
+	entry := &lruEntry{
+		key:      lruKey{h: h, sql: sql},
+		stmt:     stmt,
+		accessed: now,
+	}
+	v[sql] = p.lru.PushFront(entry)
+
+	p.evict(now)
+	return entry
+}
+
+func (p *lruPool) acquire(entry *lruEntry) func() {
+	entry.refs++
+	once := sync.Once{}
+	return func() {
+		once.Do(func() {
+			p.locker.Lock()
+			defer p.locker.Unlock()
+
+			entry.refs--
+			if entry.refs == 0 && entry.removed {
+				_ = entry.stmt.Close()
+			}
+		})
+	}
+}
+
+func (p *lruPool) Purge(h Handler) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	for _, e := range p.elements[h] {
+		p.remove(e)
+	}
+	delete(p.elements, h)
+}
+
+func (p *lruPool) PurgeAll() {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	for e := p.lru.Front(); e != nil; e = e.Next() {
+		p.close(e.Value.(*lruEntry))
+	}
+	p.lru.Init()
+	p.elements = map[Handler]map[string]*list.Element{}
+}
+
+func (p *lruPool) Stats() PoolStats {
+	p.locker.Lock()
+	size := p.lru.Len()
+	p.locker.Unlock()
+
+	return PoolStats{
+		Hits:      atomic.LoadInt64(&p.hits),
+		Misses:    atomic.LoadInt64(&p.misses),
+		Evictions: atomic.LoadInt64(&p.evictions),
+		Size:      size,
+	}
+}
+
+func (p *lruPool) evict(now time.Time) {
+	for p.lru.Len() > 0 {
+		e := p.lru.Back()
+		if p.lru.Len() <= p.capacity && (p.ttl <= 0 || now.Sub(e.Value.(*lruEntry).accessed) <= p.ttl) {
+			return
+		}
+		p.remove(e)
+		atomic.AddInt64(&p.evictions, 1)
+	}
+}
+
+func (p *lruPool) remove(e *list.Element) {
+	entry := p.lru.Remove(e).(*lruEntry)
+	if v, ok := p.elements[entry.key.h]; ok {
+		delete(v, entry.key.sql)
+		if len(v) == 0 {
+			delete(p.elements, entry.key.h)
+		}
+	}
+	p.close(entry)
+}
+
+func (p *lruPool) close(entry *lruEntry) {
+	entry.removed = true
+	if entry.refs == 0 {
+		_ = entry.stmt.Close()
+	}
+}
+
+type lruPoolOpts struct{}
+
+var LRUPoolOpts lruPoolOpts
+
+// SetTTL sets the max idle time of cached statements, 0 means never expire.
+func (lruPoolOpts) SetTTL(ttl time.Duration) LRUPoolOpt {
+	return func(pool *lruPool) {
+		pool.ttl = ttl
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"context"
+	"github.com/xfali/lean/resultset"
+	"testing"
+	"time"
+)
+
+type testHandler struct {
+	Handler
+	name string
+}
+
+type testStatement struct {
+	closed bool
+}
+
+func (s *testStatement) Query(ctx context.Context, params ...interface{}) (resultset.Result, error) {
+	return nil, nil
+}
+
+func (s *testStatement) Execute(ctx context.Context, params ...interface{}) (resultset.Result, error) {
+	return nil, nil
+}
+
+func (s *testStatement) ExecuteBatch(ctx context.Context, params [][]interface{}) (*resultset.BatchResult, error) {
+	return nil, nil
+}
+
+func (s *testStatement) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestLRUPool(t *testing.T) {
+	h1, h2 := &testHandler{name: "h1"}, &testHandler{name: "h2"}
+	t.Run("capacity", func(t *testing.T) {
+		p := NewLRUPool(2)
+		s1, s2, s3 := &testStatement{}, &testStatement{}, &testStatement{}
+		p.Put(h1, "s1", s1)
+		p.Put(h2, "s2", s2)
+		if _, ok := p.Get(h1, "s1"); !ok {
+			t.Fatal("expect s1")
+		}
+		p.Put(h1, "s3", s3)
+		if _, ok := p.Get(h2, "s2"); ok || !s2.closed {
+			t.Fatal("expect s2 evicted")
+		}
+		if _, ok := p.Get(h1, "s1"); !ok || s1.closed {
+			t.Fatal("expect s1")
+		}
+		stats := p.Stats()
+		if stats.Hits != 2 || stats.Misses != 1 || stats.Evictions != 1 || stats.Size != 2 {
+			t.Fatal(stats)
+		}
+
+		p.Purge(h1)
+		if !s1.closed || !s3.closed || p.Stats().Size != 0 {
+			t.Fatal("expect purged")
+		}
+	})
+	t.Run("ttl", func(t *testing.T) {
+		p := NewLRUPool(10, LRUPoolOpts.SetTTL(10*time.Millisecond))
+		s1 := &testStatement{}
+		p.Put(h1, "s1", s1)
+		time.Sleep(20 * time.Millisecond)
+		if _, ok := p.Get(h1, "s1"); ok || !s1.closed {
+			t.Fatal("expect s1 expired")
+		}
+		if p.Stats().Evictions != 1 {
+			t.Fatal(p.Stats())
+		}
+	})
+	t.Run("purge all", func(t *testing.T) {
+		p := NewLRUPool(10)
+		ss := []*testStatement{{}, {}}
+		p.Put(h1, "s1", ss[0])
+		p.Put(h2, "s1", ss[1])
+		p.PurgeAll()
+		for _, s := range ss {
+			if !s.closed {
+				t.Fatal("expect closed")
+			}
+		}
+	})
+}
@@ -31,6 +31,18 @@ type StatementPool interface {
 	PurgeAll()
 }
 
+// SharedStatementPool is a StatementPool which can be shared by goroutines. Statements got by Acquire or
+// PutAndAcquire are not closed by eviction or purge until the returned release function is called.
+type SharedStatementPool interface {
+	StatementPool
+
+	Acquire(h Handler, sql string) (statement.Statement, func(), bool)
+
+	// PutAndAcquire caches and acquires stmt. If a statement with the same sql has been cached by another
+	// goroutine, stmt is closed and the cached one is returned.
+	PutAndAcquire(h Handler, sql string, stmt statement.Statement) (statement.Statement, func())
+}
+
 type defaultPool struct {
 	stmtMap    map[Handler]map[string]statement.Statement
 	stmtLocker sync.RWMutex
@@ -0,0 +1,111 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resultset
+
+// BatchItem is the result of one params set of a batch, RowsAffected is -1 if unknown.
+type BatchItem struct {
+	RowsAffected int64
+	Err          error
+}
+
+type BatchResult struct {
+	Items []BatchItem
+
+	lastInsertId int64
+}
+
+func NewBatchResult(size int) *BatchResult {
+	return &BatchResult{
+		Items: make([]BatchItem, 0, size),
+	}
+}
+
+// Add records the result of next item.
+func (r *BatchResult) Add(ret ExecResult, err error) {
+	item := BatchItem{
+		RowsAffected: -1,
+		Err:          err,
+	}
+	if err == nil && ret != nil {
+		if n, e := ret.RowsAffected(); e == nil {
+			item.RowsAffected = n
+		}
+		if id, e := ret.LastInsertId(); e == nil {
+			r.lastInsertId = id
+		}
+	}
+	r.Items = append(r.Items, item)
+}
+
+// AddChunk records the result of size items executed by one statement, the rows affected of each item is unknown.
+func (r *BatchResult) AddChunk(size int, ret ExecResult, err error) {
+	n := int64(-1)
+	if err == nil && ret != nil {
+		if v, e := ret.RowsAffected(); e == nil {
+			n = v
+		}
+		if id, e := ret.LastInsertId(); e == nil {
+			r.lastInsertId = id
+		}
+	}
+	for i := 0; i < size; i++ {
+		item := BatchItem{
+			RowsAffected: -1,
+			Err:          err,
+		}
+		// the total rows affected of the chunk is recorded by the first item
+		if i == 0 {
+			item.RowsAffected = n
+		}
+		r.Items = append(r.Items, item)
+	}
+}
+
+func (r *BatchResult) LastInsertId() (int64, error) {
+	return r.lastInsertId, nil
+}
+
+// RowsAffected returns the total rows affected of the items which are known.
+func (r *BatchResult) RowsAffected() (int64, error) {
+	var n int64
+	for _, v := range r.Items {
+		if v.RowsAffected > 0 {
+			n += v.RowsAffected
+		}
+	}
+	return n, nil
+}
+
+// Rollback marks the succeeded items failed with err after the transaction of batch is rolled back.
+func (r *BatchResult) Rollback(err error) {
+	for i := range r.Items {
+		if r.Items[i].Err == nil {
+			r.Items[i] = BatchItem{RowsAffected: -1, Err: err}
+		}
+	}
+	r.lastInsertId = 0
+}
+
+func (r *BatchResult) Failed() int {
+	n := 0
+	for _, v := range r.Items {
+		if v.Err != nil {
+			n++
+		}
+	}
+	return n
+}
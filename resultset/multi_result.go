@@ -0,0 +1,84 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resultset
+
+// MultiResult concatenates rows of results in order, columns are taken from the first result.
+type MultiResult struct {
+	results []Result
+	index   int
+}
+
+func NewMultiResult(results ...Result) *MultiResult {
+	return &MultiResult{
+		results: results,
+	}
+}
+
+func (r *MultiResult) Columns() ([]string, error) {
+	if len(r.results) == 0 {
+		return nil, nil
+	}
+	return r.results[0].Columns()
+}
+
+func (r *MultiResult) Next() bool {
+	for r.index < len(r.results) {
+		if r.results[r.index].Next() {
+			return true
+		}
+		_ = r.results[r.index].Close()
+		r.index++
+	}
+	return false
+}
+
+func (r *MultiResult) Scan(dest ...interface{}) error {
+	if r.index >= len(r.results) {
+		return nil
+	}
+	return r.results[r.index].Scan(dest...)
+}
+
+func (r *MultiResult) Close() error {
+	var ret error
+	for ; r.index < len(r.results); r.index++ {
+		if err := r.results[r.index].Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+func (r *MultiResult) LastInsertId() (int64, error) {
+	if len(r.results) == 0 {
+		return 0, nil
+	}
+	return r.results[len(r.results)-1].LastInsertId()
+}
+
+// RowsAffected returns the sum of rows affected of all results.
+func (r *MultiResult) RowsAffected() (int64, error) {
+	var ret int64
+	for _, v := range r.results {
+		n, err := v.RowsAffected()
+		if err != nil {
+			return ret, err
+		}
+		ret += n
+	}
+	return ret, nil
+}
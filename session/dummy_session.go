@@ -53,6 +53,17 @@ func (d dummySession) Execute(ctx context.Context, stmt string, params ...interf
 	return nil, nil
 }
 
+func (d dummySession) ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error) {
+	if d.sleep > 0 {
+		time.Sleep(d.sleep)
+	}
+	ret := resultset.NewBatchResult(len(params))
+	for range params {
+		ret.Add(nil, nil)
+	}
+	return ret, nil
+}
+
 func (d dummySession) Begin(ctx context.Context) error {
 	if d.sleep > 0 {
 		time.Sleep(d.sleep)
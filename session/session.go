@@ -28,6 +28,8 @@ type Session interface {
 
 	Execute(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error)
 
+	ExecuteBatch(ctx context.Context, stmt string, params [][]interface{}) (*resultset.BatchResult, error)
+
 	Begin(ctx context.Context) error
 
 	Commit(ctx context.Context) error
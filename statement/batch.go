@@ -0,0 +1,79 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package statement
+
+import (
+	"context"
+	"github.com/xfali/lean/errors"
+	"github.com/xfali/lean/resultset"
+)
+
+type BatchMode int
+
+const (
+	// BatchPrepared executes every params set with one prepared statement.
+	BatchPrepared BatchMode = iota
+	// BatchRewrite rewrites INSERT ... VALUES (...) statement into multi-values INSERT of ChunkSize rows.
+	BatchRewrite
+)
+
+const (
+	DefaultBatchChunkSize = 100
+)
+
+type BatchOption struct {
+	Mode      BatchMode
+	ChunkSize int
+	// ContinueOnError executes the rest items when one failed, the errors are recorded in the result.
+	ContinueOnError bool
+}
+
+type batchOptionKey struct{}
+
+func WithBatchOption(ctx context.Context, opt BatchOption) context.Context {
+	return context.WithValue(ctx, batchOptionKey{}, opt)
+}
+
+func GetBatchOption(ctx context.Context) BatchOption {
+	if v, ok := ctx.Value(batchOptionKey{}).(BatchOption); ok {
+		if v.ChunkSize <= 0 {
+			v.ChunkSize = DefaultBatchChunkSize
+		}
+		return v
+	}
+	return BatchOption{
+		Mode:      BatchPrepared,
+		ChunkSize: DefaultBatchChunkSize,
+	}
+}
+
+// RunBatch executes params one by one with execute, it can be used to implement Statement.ExecuteBatch.
+func RunBatch(ctx context.Context, params [][]interface{}, execute func(ctx context.Context, params ...interface{}) (resultset.Result, error)) (*resultset.BatchResult, error) {
+	opt := GetBatchOption(ctx)
+	ret := resultset.NewBatchResult(len(params))
+	for _, p := range params {
+		r, err := execute(ctx, p...)
+		ret.Add(r, err)
+		if err != nil && !opt.ContinueOnError {
+			return ret, err
+		}
+	}
+	if ret.Failed() > 0 {
+		return ret, errors.BatchPartialError
+	}
+	return ret, nil
+}
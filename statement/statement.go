@@ -24,5 +24,6 @@ import (
 type Statement interface {
 	Query(ctx context.Context, params ...interface{}) (resultset.Result, error)
 	Execute(ctx context.Context, params ...interface{}) (resultset.Result, error)
+	ExecuteBatch(ctx context.Context, params [][]interface{}) (*resultset.BatchResult, error)
 	Close() error
 }
@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package statement
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"github.com/xfali/lean/errors"
+	"time"
+)
+
+type timeoutKey struct{}
+
+// WithTimeout overrides the default statement timeout of session for the statements executed with ctx,
+// timeout <= 0 disables the default one. The deadline of ctx is always respected.
+func WithTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+// WithDeadline returns ctx bounded by the timeout set by WithTimeout, or by defaultTimeout if ctx has neither
+// the timeout nor a deadline. cancel must be called after the statement is finished.
+func WithDeadline(ctx context.Context, defaultTimeout time.Duration) (context.Context, context.CancelFunc) {
+	timeout := defaultTimeout
+	if v, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		timeout = v
+	} else if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// CheckTimeout returns errors.StatementTimeout wrapping err if err is caused by the deadline of ctx.
+func CheckTimeout(ctx context.Context, err error) error {
+	if err == nil || stderrors.Is(err, errors.StatementTimeout) {
+		return err
+	}
+	if stderrors.Is(ctx.Err(), context.DeadlineExceeded) || stderrors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v ", errors.StatementTimeout, err)
+	}
+	return err
+}
@@ -20,9 +20,15 @@ import (
 	"context"
 	"fmt"
 	"github.com/xfali/aop"
+	"github.com/xfali/lean/drivers/sqldrv"
 	"github.com/xfali/lean/executor"
 	"github.com/xfali/lean/extensions"
+	"github.com/xfali/lean/handler"
+	"github.com/xfali/lean/resultset"
 	"github.com/xfali/lean/session"
+	"github.com/xfali/xlog"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -112,3 +118,305 @@ func runSession(sess session.Session) {
 	sess.Rollback(ctx)
 	sess.Close()
 }
+
+func TestSessionChain(t *testing.T) {
+	var calls []string
+	trace := func(name string) extensions.Interceptor {
+		return extensions.Interceptor{
+			Query: func(next extensions.QueryFunc) extensions.QueryFunc {
+				return func(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+					calls = append(calls, name)
+					return next(ctx, stmt, params...)
+				}
+			},
+		}
+	}
+	sess := extensions.NewSessionChain(session.NewDummySession(0), trace("first"), trace("second"))
+	sess.Extend(extensions.PointCutStatement(), func(invocation aop.Invocation, params []interface{}) []interface{} {
+		calls = append(calls, "advice:"+invocation.MethodName())
+		call := extensions.ParseCall(invocation.MethodName(), params)
+		if len(call.Params) != 2 {
+			t.Fatal("expect 2 params but get ", call.Params)
+		}
+		return invocation.Invoke(params)
+	})
+	runSession(sess)
+	if strings.Join(calls, ",") != "first,second,advice:Query,advice:Execute" {
+		t.Fatal(calls)
+	}
+	runSession(createSessionChain(t.Log))
+}
+
+func TestExecutorChain(t *testing.T) {
+	runExecutor(createExecutorChain(t.Log))
+}
+
+func BenchmarkChainSession(t *testing.B) {
+	for i := 0; i < t.N; i++ {
+		runSession(extensions.NewSessionChain(session.NewDummySession(2*time.Millisecond), interceptor(none)))
+	}
+}
+
+func BenchmarkChainAdviceSession(t *testing.B) {
+	for i := 0; i < t.N; i++ {
+		runSession(createSessionChain(none))
+	}
+}
+
+func BenchmarkChainExecutor(t *testing.B) {
+	for i := 0; i < t.N; i++ {
+		runExecutor(extensions.NewExecutorChain(executor.NewDummyExecutor(2*time.Millisecond), interceptor(none)))
+	}
+}
+
+func interceptor(t func(...any)) extensions.Interceptor {
+	return extensions.Interceptor{
+		Query: func(next extensions.QueryFunc) extensions.QueryFunc {
+			return func(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+				t("Query Params: ", stmt, params)
+				return next(ctx, stmt, params...)
+			}
+		},
+		Execute: func(next extensions.ExecuteFunc) extensions.ExecuteFunc {
+			return func(ctx context.Context, stmt string, params ...interface{}) (resultset.Result, error) {
+				t("Execute Params: ", stmt, params)
+				return next(ctx, stmt, params...)
+			}
+		},
+	}
+}
+
+func createSessionChain(t func(...any)) session.Session {
+	sess := extensions.NewSessionChain(session.NewDummySession(2 * time.Millisecond))
+	sess.Extend(aop.PointCutRegExp("", "(.*?)", nil, nil), func(invocation aop.Invocation, params []interface{}) (ret []interface{}) {
+		t(invocation.MethodName(), " Params: ", fmt.Sprintln(params...))
+		ret = invocation.Invoke(params)
+		t(invocation.MethodName(), " results: ", fmt.Sprintln(ret...))
+		return ret
+	})
+	return sess
+}
+
+func createExecutorChain(t func(...any)) executor.Executor {
+	exec := extensions.NewExecutorChain(executor.NewDummyExecutor(2 * time.Millisecond))
+	exec.Extend(aop.PointCutRegExp("", "(.*?)", nil, nil), func(invocation aop.Invocation, params []interface{}) (ret []interface{}) {
+		t(invocation.MethodName(), " Params: ", fmt.Sprintln(params...))
+		ret = invocation.Invoke(params)
+		t(invocation.MethodName(), " results: ", fmt.Sprintln(ret...))
+		return ret
+	})
+	return exec
+}
+
+func TestExtensionRewriteParams(t *testing.T) {
+	db, dsn := newFakeDB("rewrite_params")
+	conn := sqldrv.NewSqlConnection(FakeDriverName, dsn)
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	s, err := conn.GetSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := extensions.NewSessionEx(s)
+	defer sess.Close()
+	sess.Extend(extensions.PointCutStatement(), func(invocation aop.Invocation, params []interface{}) []interface{} {
+		if len(params) != 4 {
+			t.Fatal("expect flat params but get ", params)
+		}
+		params[1] = params[1].(string) + " and tenant = ?"
+		params[3] = params[3].(int) * 100
+		return invocation.Invoke(append(params, "t1"))
+	})
+
+	ctx := context.Background()
+	if _, err := sess.Execute(ctx, "update tbl set name = ? where id = ?", "a", 1); err != nil {
+		t.Fatal(err)
+	}
+	r, err := sess.Query(ctx, "select * from tbl where name = ? and id = ?", "b", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if len(db.Execs) != 1 || db.Execs[0].Query != "update tbl set name = ? where id = ? and tenant = ?" ||
+		fmt.Sprint(db.Execs[0].Args) != "[a 100 t1]" {
+		t.Fatal("expect rewritten exec but get ", db.Execs)
+	}
+	if len(db.Queries) != 1 || db.Queries[0].Query != "select * from tbl where name = ? and id = ? and tenant = ?" ||
+		fmt.Sprint(db.Queries[0].Args) != "[b 200 t1]" {
+		t.Fatal("expect rewritten query but get ", db.Queries)
+	}
+}
+
+func TestConnectionExtension(t *testing.T) {
+	db, dsn := newFakeDB("connection_ex")
+	var locker sync.Mutex
+	calls := map[string]int{}
+	conn := extensions.NewConnectionEx(sqldrv.NewSqlConnection(FakeDriverName, dsn,
+		sqldrv.ConnOpts.SetStatementPool(handler.NewLRUPool(16))))
+	conn.Extend(aop.PointCutRegExp("", ".*", nil, nil), func(invocation aop.Invocation, params []interface{}) []interface{} {
+		locker.Lock()
+		calls[invocation.MethodName()]++
+		locker.Unlock()
+		return invocation.Invoke(params)
+	})
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		sess, err := conn.GetSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sess.Execute(ctx, "update tbl set name = ? where id = ?", "a", i); err != nil {
+			t.Fatal(err)
+		}
+		if err := sess.Begin(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sess.Execute(ctx, "update tbl set name = ? where id = ?", "b", i); err != nil {
+			t.Fatal(err)
+		}
+		if err := sess.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+		sess.Close()
+	}
+	// connection + 2 sessions
+	if calls[extensions.MethodOpen] != 1 || calls[extensions.MethodGetSession] != 2 {
+		t.Fatal(calls)
+	}
+	// session and statement, statement prepared once is shared by the extended handlers of sessions
+	if calls[extensions.MethodExecute] != 8 || calls[extensions.MethodPrepare] != 1 {
+		t.Fatal(calls)
+	}
+	// session, transaction
+	if calls[extensions.MethodBegin] != 4 || calls[extensions.MethodCommit] != 4 {
+		t.Fatal(calls)
+	}
+	if calls[extensions.MethodGetHandler] == 0 {
+		t.Fatal(calls)
+	}
+	if db.Commits != 2 || db.ExecCount() != 4 {
+		t.Fatal("expect 2 commits and 4 execs ", db.Commits, db.ExecCount())
+	}
+}
+
+type captureLogger struct {
+	xlog.Logger
+	locker sync.Mutex
+	logs   map[string][]string
+}
+
+func newCaptureLogger() *captureLogger {
+	return &captureLogger{logs: map[string][]string{}}
+}
+
+func (l *captureLogger) add(level string, args ...interface{}) {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	l.logs[level] = append(l.logs[level], fmt.Sprint(args...))
+}
+
+func (l *captureLogger) Infoln(args ...interface{})  { l.add("info", args...) }
+func (l *captureLogger) Warnln(args ...interface{})  { l.add("warn", args...) }
+func (l *captureLogger) Errorln(args ...interface{}) { l.add("error", args...) }
+
+func TestLoggingExtension(t *testing.T) {
+	log := newCaptureLogger()
+	sess := extensions.NewSessionEx(session.NewDummySession(5 * time.Millisecond))
+	extensions.WithLogging(sess,
+		extensions.LogOpts.SetLogger(log),
+		extensions.LogOpts.SetSlowThreshold(time.Millisecond),
+		extensions.LogOpts.SetRedactColumns("password"))
+
+	ctx := context.Background()
+	sess.Execute(ctx, "update users set password = ? where id = ?", "secret", 1)
+	sess.Execute(ctx, "insert into users (name, `password`) values (?, ?)", "tom", "secret")
+	sess.Ping(ctx)
+	if len(log.logs["warn"]) != 2 {
+		t.Fatal("expect 2 slow logs but get ", log.logs)
+	}
+	for _, v := range log.logs["warn"] {
+		t.Log(v)
+		if strings.Contains(v, "secret") || !strings.Contains(v, extensions.RedactedValue) {
+			t.Fatal("expect password redacted ", v)
+		}
+	}
+
+	log = newCaptureLogger()
+	sess = extensions.NewSessionEx(session.NewDummySession(0))
+	extensions.WithLogging(sess,
+		extensions.LogOpts.SetLogger(log),
+		extensions.LogOpts.SetRedactColumns("password"))
+	batch := [][]interface{}{{"tom", "secret"}, {"jerry", "secret"}}
+	sess.ExecuteBatch(ctx, "insert into users (name, password) values (?, ?)", batch)
+	if v := log.logs["info"]; len(v) != 1 || !strings.Contains(v[0], "first of 2") || strings.Contains(v[0], "jerry") {
+		t.Fatal("expect first params of batch logged ", v)
+	}
+	log = newCaptureLogger()
+	sess = extensions.NewSessionEx(session.NewDummySession(0))
+	extensions.WithLogging(sess,
+		extensions.LogOpts.SetLogger(log),
+		extensions.LogOpts.SetLogBatchParams(true),
+		extensions.LogOpts.SetRedactColumns("password"))
+	sess.ExecuteBatch(ctx, "insert into users (name, password) values (?, ?)", batch)
+	if v := log.logs["info"]; len(v) != 1 || !strings.Contains(v[0], "jerry") || strings.Contains(v[0], "secret") {
+		t.Fatal("expect all params of batch logged and redacted ", v)
+	}
+
+	log = newCaptureLogger()
+	sess = extensions.NewSessionEx(session.NewDummySession(0))
+	extensions.WithLogging(sess,
+		extensions.LogOpts.SetLogger(log),
+		extensions.LogOpts.SetSampleRate(0.25))
+	for i := 0; i < 8; i++ {
+		sess.Query(ctx, "select * from users where id = ?", i)
+	}
+	if len(log.logs["info"]) != 2 {
+		t.Fatal("expect 2 sampled logs but get ", log.logs)
+	}
+}
+
+func TestParamColumns(t *testing.T) {
+	cols := extensions.ParamColumns("select * from users u where u.name = ? and `password` <> ? and age + 1 > ?", []interface{}{"a", "b", 1})
+	if cols[0] != "name" || cols[1] != "password" || cols[2] != "" {
+		t.Fatal(cols)
+	}
+	cols = extensions.ParamColumns("update users set name = $2 where password = $1", []interface{}{"a", "b"})
+	if cols[0] != "password" || cols[1] != "name" {
+		t.Fatal(cols)
+	}
+	cols = extensions.ParamColumns("MATCH (v:user) WHERE v.user.password == $pwd RETURN v", []interface{}{"pwd", "x"})
+	if cols[1] != "pwd" {
+		t.Fatal(cols)
+	}
+	cols = extensions.ParamColumns("INSERT INTO u (name, created, password) VALUES (?, now(), ?), (?, now(), lower(?))", []interface{}{"a", "b", "c", "d"})
+	if cols[0] != "name" || cols[1] != "password" || cols[2] != "name" || cols[3] != "password" {
+		t.Fatal(cols)
+	}
+	cols = extensions.ParamColumns("update u set password = md5(?) where id in (?, ?)", []interface{}{"a", 1, 2})
+	if cols[0] != "password" || cols[1] != "id" || cols[2] != "id" {
+		t.Fatal(cols)
+	}
+
+	redact := extensions.RedactColumns("password")
+	if v := redact("update u set password = concat(?, ?)", 1, "", "x"); v != extensions.RedactedValue {
+		t.Fatal("expect value of unknown column redacted but get ", v)
+	}
+	if v := redact("update u set name = ? where id + 1 > ?", 1, "", 2); v != 2 {
+		t.Fatal("expect value logged but get ", v)
+	}
+	type user struct {
+		Name     string
+		Password string `column:"pwd"`
+	}
+	v := redact("INSERT VERTEX user(name, pwd) VALUES \"u1\":($Name, $pwd)", 0, "", &user{Name: "tom", Password: "x"})
+	if m, ok := v.(map[string]interface{}); !ok || m["Name"] != "tom" || m["pwd"] != extensions.RedactedValue {
+		t.Fatal("expect password field redacted but get ", v)
+	}
+}
@@ -0,0 +1,276 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const FakeDriverName = "lean_fake"
+
+func init() {
+	sql.Register(FakeDriverName, &fakeDriver{})
+}
+
+type fakeCall struct {
+	Query string
+	Args  []driver.Value
+	InTx  bool
+}
+
+// fakeDB records the calls of database/sql, query results are taken from Columns and Rows.
+type fakeDB struct {
+	locker sync.Mutex
+
+	Columns []string
+	Rows    [][]driver.Value
+	// ExecError makes Exec fail if the query contains it.
+	ExecError string
+	// ExecErrorArg makes Exec fail if one of the args equals it.
+	ExecErrorArg driver.Value
+	// CommitError makes Commit fail.
+	CommitError bool
+	// Down makes Ping fail.
+	Down bool
+	// Delay delays ExecContext and QueryContext until ctx is done.
+	Delay time.Duration
+
+	Prepares   int
+	StmtCloses int
+	Begins     int
+	Commits    int
+	Rollbacks  int
+	Execs      []fakeCall
+	Queries    []fakeCall
+}
+
+var (
+	fakeDBs      = map[string]*fakeDB{}
+	fakeDBLocker sync.Mutex
+)
+
+// newFakeDB registers a fakeDB and returns the dsn to open it.
+func newFakeDB(name string) (*fakeDB, string) {
+	fakeDBLocker.Lock()
+	defer fakeDBLocker.Unlock()
+
+	db := &fakeDB{}
+	dsn := fmt.Sprintf("%s_%d", name, len(fakeDBs))
+	fakeDBs[dsn] = db
+	return db, dsn
+}
+
+func (db *fakeDB) ExecCount() int {
+	db.locker.Lock()
+	defer db.locker.Unlock()
+	return len(db.Execs)
+}
+
+func (db *fakeDB) QueryCount() int {
+	db.locker.Lock()
+	defer db.locker.Unlock()
+	return len(db.Queries)
+}
+
+type fakeDriver struct{}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBLocker.Lock()
+	defer fakeDBLocker.Unlock()
+
+	db, ok := fakeDBs[name]
+	if !ok {
+		return nil, fmt.Errorf("fake db %s not found", name)
+	}
+	return &fakeConn{db: db}, nil
+}
+
+type fakeConn struct {
+	db   *fakeDB
+	inTx bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.db.locker.Lock()
+	defer c.db.locker.Unlock()
+	c.db.Prepares++
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Ping(ctx context.Context) error {
+	c.db.locker.Lock()
+	defer c.db.locker.Unlock()
+	if c.db.Down {
+		return fmt.Errorf("fake db down")
+	}
+	return nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.locker.Lock()
+	defer c.db.locker.Unlock()
+	c.db.Begins++
+	c.inTx = true
+	return &fakeTx{conn: c}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.delay(ctx); err != nil {
+		return nil, err
+	}
+	return c.exec(query, args)
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.delay(ctx); err != nil {
+		return nil, err
+	}
+	return c.query(query, args)
+}
+
+func (c *fakeConn) delay(ctx context.Context) error {
+	if c.db.Delay <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(c.db.Delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *fakeConn) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.locker.Lock()
+	defer c.db.locker.Unlock()
+	if c.db.ExecError != "" && strings.Contains(query, c.db.ExecError) {
+		return nil, fmt.Errorf("fake exec error: %s", query)
+	}
+	if c.db.ExecErrorArg != nil {
+		for _, v := range args {
+			if v.Value == c.db.ExecErrorArg {
+				return nil, fmt.Errorf("fake exec error: %v", v.Value)
+			}
+		}
+	}
+	c.db.Execs = append(c.db.Execs, fakeCall{Query: query, Args: values(args), InTx: c.inTx})
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.locker.Lock()
+	defer c.db.locker.Unlock()
+	c.db.Queries = append(c.db.Queries, fakeCall{Query: query, Args: values(args), InTx: c.inTx})
+	return &fakeRows{columns: c.db.Columns, rows: c.db.Rows}, nil
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	ret := make([]driver.Value, len(args))
+	for i := range args {
+		ret[i] = args[i].Value
+	}
+	return ret
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (t *fakeTx) Commit() error {
+	t.conn.db.locker.Lock()
+	defer t.conn.db.locker.Unlock()
+	t.conn.inTx = false
+	if t.conn.db.CommitError {
+		return fmt.Errorf("fake commit error")
+	}
+	t.conn.db.Commits++
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.conn.db.locker.Lock()
+	defer t.conn.db.locker.Unlock()
+	t.conn.db.Rollbacks++
+	t.conn.inTx = false
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	s.conn.db.locker.Lock()
+	defer s.conn.db.locker.Unlock()
+	s.conn.db.StmtCloses++
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.exec(s.query, named(args))
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.query(s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	ret := make([]driver.NamedValue, len(args))
+	for i := range args {
+		ret[i] = driver.NamedValue{Ordinal: i + 1, Value: args[i]}
+	}
+	return ret
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	index   int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.index >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.index])
+	r.index++
+	return nil
+}
@@ -0,0 +1,623 @@
+/*
+ * Copyright (C) 2023-2025, Xiongfa Li.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"context"
+	"database/sql/driver"
+	stderrors "errors"
+	"github.com/xfali/lean/connection"
+	"github.com/xfali/lean/drivers/sqldrv"
+	"github.com/xfali/lean/errors"
+	"github.com/xfali/lean/executor"
+	"github.com/xfali/lean/handler"
+	"github.com/xfali/lean/statement"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSqlSharedStatementPool(t *testing.T) {
+	db, dsn := newFakeDB("shared_pool")
+	pool := handler.NewLRUPool(16)
+	conn := sqldrv.NewSqlConnection(FakeDriverName, dsn,
+		sqldrv.ConnOpts.SetMaxConn(1),
+		sqldrv.ConnOpts.SetMaxIdleConn(1),
+		sqldrv.ConnOpts.SetStatementPool(pool))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		sess, err := conn.GetSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sess.Execute(ctx, "update tbl set name = ? where id = ?", "hello", i); err != nil {
+			t.Fatal(err)
+		}
+		if err := sess.Begin(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sess.Execute(ctx, "update tbl set name = ? where id = ?", "world", i); err != nil {
+			t.Fatal(err)
+		}
+		if err := sess.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sess.Execute(ctx, "update tbl set name = ? where id = ?", "after", i); err != nil {
+			t.Fatal(err)
+		}
+		if err := sess.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if db.ExecCount() != 9 {
+		t.Fatal("expect 9 execs but get ", db.ExecCount())
+	}
+	if !db.Execs[1].InTx || db.Execs[2].InTx {
+		t.Fatal("expect second exec in transaction ", db.Execs)
+	}
+	if db.Prepares != 1 {
+		t.Fatal("expect prepared once but get ", db.Prepares)
+	}
+	stats := pool.Stats()
+	if stats.Size != 1 {
+		t.Fatal("expect only db statement cached ", stats)
+	}
+	t.Log(stats)
+
+	// Commit only releases the statements bound to transaction
+	if db.StmtCloses != 0 {
+		t.Fatal("expect statement not closed but get ", db.StmtCloses)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if db.StmtCloses != 1 {
+		t.Fatal("expect statement closed once but get ", db.StmtCloses)
+	}
+}
+
+func TestSqlExecuteBatch(t *testing.T) {
+	rows := [][]interface{}{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}, {"e", 5}}
+	newConn := func(name string) (*fakeDB, connection.Connection) {
+		db, dsn := newFakeDB(name)
+		conn := sqldrv.NewSqlConnection(FakeDriverName, dsn,
+			sqldrv.ConnOpts.SetMaxConn(1),
+			sqldrv.ConnOpts.SetMaxIdleConn(1))
+		if err := conn.Open(); err != nil {
+			t.Fatal(err)
+		}
+		return db, conn
+	}
+
+	t.Run("prepared", func(t *testing.T) {
+		db, conn := newConn("batch_prepared")
+		defer conn.Close()
+		sess, err := conn.GetSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sess.Close()
+		ret, err := sess.ExecuteBatch(context.Background(), "insert into tbl (name, id) values (?, ?)", rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, _ := ret.RowsAffected(); n != 5 || len(ret.Items) != 5 {
+			t.Fatal("expect 5 rows affected but get ", n, ret.Items)
+		}
+		if db.ExecCount() != 5 || db.Begins != 1 || db.Commits != 1 {
+			t.Fatal("expect 5 execs in one transaction ", db.ExecCount(), db.Begins, db.Commits)
+		}
+		for _, v := range db.Execs {
+			if !v.InTx {
+				t.Fatal("expect exec in transaction ", v)
+			}
+		}
+	})
+
+	t.Run("rewrite", func(t *testing.T) {
+		db, conn := newConn("batch_rewrite")
+		defer conn.Close()
+		sess, err := conn.GetSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sess.Close()
+		ctx := statement.WithBatchOption(context.Background(), statement.BatchOption{
+			Mode:      statement.BatchRewrite,
+			ChunkSize: 2,
+		})
+		ret, err := sess.ExecuteBatch(ctx, "insert into tbl (name, id) values (?, ?)", rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ret.Items) != 5 {
+			t.Fatal("expect 5 items but get ", ret.Items)
+		}
+		if db.ExecCount() != 3 {
+			t.Fatal("expect 3 execs but get ", db.ExecCount())
+		}
+		if db.Execs[0].Query != "insert into tbl (name, id) values (?, ?), (?, ?)" || len(db.Execs[0].Args) != 4 {
+			t.Fatal("unexpected rewritten statement ", db.Execs[0])
+		}
+		if db.Execs[2].Query != "insert into tbl (name, id) values (?, ?)" {
+			t.Fatal("unexpected rewritten statement ", db.Execs[2])
+		}
+	})
+
+	t.Run("stop on error", func(t *testing.T) {
+		db, conn := newConn("batch_stop")
+		defer conn.Close()
+		db.ExecErrorArg = "c"
+		sess, err := conn.GetSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sess.Close()
+		ret, err := sess.ExecuteBatch(context.Background(), "insert into tbl (name, id) values (?, ?)", rows)
+		if err == nil {
+			t.Fatal("expect error")
+		}
+		if len(ret.Items) != 3 || ret.Items[2].Err == nil {
+			t.Fatal("expect stopped at third item ", ret.Items)
+		}
+		if !stderrors.Is(ret.Items[0].Err, errors.BatchRolledBack) || ret.Failed() != 3 {
+			t.Fatal("expect items before error rolled back ", ret.Items)
+		}
+		if n, _ := ret.RowsAffected(); n != 0 {
+			t.Fatal("expect no rows affected after rollback but get ", n)
+		}
+		if db.Rollbacks != 1 || db.Commits != 0 {
+			t.Fatal("expect rolled back ", db.Rollbacks, db.Commits)
+		}
+	})
+
+	t.Run("commit error", func(t *testing.T) {
+		db, conn := newConn("batch_commit")
+		defer conn.Close()
+		db.CommitError = true
+		sess, err := conn.GetSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sess.Close()
+		ctx := context.Background()
+		ret, err := sess.ExecuteBatch(ctx, "insert into tbl (name, id) values (?, ?)", rows)
+		if err == nil {
+			t.Fatal("expect commit error")
+		}
+		if len(ret.Items) != 5 || ret.Failed() != 5 || !stderrors.Is(ret.Items[0].Err, errors.BatchRolledBack) {
+			t.Fatal("expect all items rolled back ", ret.Items)
+		}
+		if _, err := sess.Execute(ctx, "update tbl set name = ? where id = ?", "after", 1); err != nil {
+			t.Fatal(err)
+		}
+		if db.Execs[len(db.Execs)-1].InTx {
+			t.Fatal("expect exec outside the failed transaction")
+		}
+		if err := sess.Begin(ctx); err != nil {
+			t.Fatal("expect transaction reset but get ", err)
+		}
+		if err := sess.Rollback(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("continue on error", func(t *testing.T) {
+		db, conn := newConn("batch_continue")
+		defer conn.Close()
+		db.ExecErrorArg = "c"
+		sess, err := conn.GetSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sess.Close()
+		ctx := statement.WithBatchOption(context.Background(), statement.BatchOption{
+			ContinueOnError: true,
+		})
+		ret, err := sess.ExecuteBatch(ctx, "insert into tbl (name, id) values (?, ?)", rows)
+		if !stderrors.Is(err, errors.BatchPartialError) {
+			t.Fatal("expect BatchPartialError but get ", err)
+		}
+		if len(ret.Items) != 5 || ret.Failed() != 1 {
+			t.Fatal("expect one failed item ", ret.Items)
+		}
+		if n, _ := ret.RowsAffected(); n != 4 {
+			t.Fatal("expect 4 rows affected but get ", n)
+		}
+		if db.Commits != 1 {
+			t.Fatal("expect committed ", db.Commits)
+		}
+	})
+}
+
+func TestRewriteInsert(t *testing.T) {
+	s, err := executor.RewriteInsert("INSERT INTO tbl (a, b) VALUES ($1, $2) ON CONFLICT DO NOTHING", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "INSERT INTO tbl (a, b) VALUES ($1, $2), ($3, $4), ($5, $6) ON CONFLICT DO NOTHING" {
+		t.Fatal(s)
+	}
+	s, err = executor.RewriteInsert("insert into tbl (a, b) values (?, '(x)')", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "insert into tbl (a, b) values (?, '(x)'), (?, '(x)')" {
+		t.Fatal(s)
+	}
+	if _, err = executor.RewriteInsert("update tbl set a = ?", 2); !stderrors.Is(err, errors.BatchRewriteError) {
+		t.Fatal("expect BatchRewriteError but get ", err)
+	}
+}
+
+func TestReadWriteConnection(t *testing.T) {
+	primary, pdsn := newFakeDB("rw_primary")
+	r1, r1dsn := newFakeDB("rw_replica")
+	r2, r2dsn := newFakeDB("rw_replica")
+	conn := connection.NewReadWriteConnection(
+		sqldrv.NewSqlConnection(FakeDriverName, pdsn),
+		[]connection.Connection{
+			sqldrv.NewSqlConnection(FakeDriverName, r1dsn),
+			sqldrv.NewSqlConnection(FakeDriverName, r2dsn),
+		},
+		connection.RWOpts.SetHealthCheckInterval(0))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	sess, err := conn.GetSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+
+	for i := 0; i < 4; i++ {
+		if _, err := sess.Query(ctx, "select * from tbl"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r1.QueryCount() != 2 || r2.QueryCount() != 2 || primary.QueryCount() != 0 {
+		t.Fatal("expect queries round robin to replicas ", r1.QueryCount(), r2.QueryCount(), primary.QueryCount())
+	}
+
+	if _, err := sess.Execute(ctx, "update tbl set name = ?", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.Query(connection.WithPrimary(ctx), "select * from tbl"); err != nil {
+		t.Fatal(err)
+	}
+	if primary.ExecCount() != 1 || primary.QueryCount() != 1 {
+		t.Fatal("expect exec and forced query to primary ", primary.ExecCount(), primary.QueryCount())
+	}
+
+	if err := sess.Begin(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.Query(ctx, "select * from tbl"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if primary.QueryCount() != 2 || !primary.Queries[1].InTx {
+		t.Fatal("expect query in transaction to primary ", primary.Queries)
+	}
+
+	r1.Down = true
+	r2.Down = true
+	if !conn.Ping(ctx) {
+		t.Fatal("expect primary healthy")
+	}
+	if h := conn.Healthy(); h[0] || h[1] {
+		t.Fatal("expect replicas unhealthy ", h)
+	}
+	if _, err := sess.Query(ctx, "select * from tbl"); err != nil {
+		t.Fatal(err)
+	}
+	if primary.QueryCount() != 3 {
+		t.Fatal("expect fallback to primary ", primary.QueryCount())
+	}
+
+	r2.Down = false
+	conn.Ping(ctx)
+	if _, err := sess.Query(ctx, "select * from tbl"); err != nil {
+		t.Fatal(err)
+	}
+	if r2.QueryCount() != 3 {
+		t.Fatal("expect query to recovered replica ", r2.QueryCount())
+	}
+}
+
+func TestReadWriteConnectionConcurrent(t *testing.T) {
+	_, pdsn := newFakeDB("rw_concurrent_primary")
+	_, rdsn := newFakeDB("rw_concurrent_replica")
+	conn := connection.NewReadWriteConnection(
+		sqldrv.NewSqlConnection(FakeDriverName, pdsn),
+		[]connection.Connection{sqldrv.NewSqlConnection(FakeDriverName, rdsn)},
+		connection.RWOpts.SetHealthCheckInterval(time.Millisecond))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	var wait sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for j := 0; j < 10; j++ {
+				if sess, err := conn.GetSession(); err == nil {
+					sess.Close()
+				}
+			}
+		}()
+	}
+	conn.Close()
+	wait.Wait()
+	if _, err := conn.GetSession(); err == nil {
+		t.Fatal("expect error after closed")
+	}
+}
+
+func TestShardConnection(t *testing.T) {
+	var dbs []*fakeDB
+	var conns []connection.Connection
+	for i := 0; i < 3; i++ {
+		db, dsn := newFakeDB("shard")
+		db.Columns = []string{"id"}
+		db.Rows = [][]driver.Value{{int64(i)}}
+		dbs = append(dbs, db)
+		conns = append(conns, sqldrv.NewSqlConnection(FakeDriverName, dsn))
+	}
+	conn := connection.NewShardConnection(conns,
+		connection.ShardOpts.SetStrategy(connection.RangeStrategy(100, 200)),
+		connection.ShardOpts.SetKeyExtractor(func(stmt string, params ...interface{}) (interface{}, bool) {
+			if len(params) == 0 {
+				return nil, false
+			}
+			return params[len(params)-1], true
+		}))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	sess, err := conn.GetSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+
+	if _, err := sess.Execute(ctx, "update tbl set name = ? where id = ?", "x", 150); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.Query(connection.WithShardKey(ctx, 250), "select * from tbl"); err != nil {
+		t.Fatal(err)
+	}
+	if dbs[1].ExecCount() != 1 || dbs[2].QueryCount() != 1 {
+		t.Fatal("expect routed by range ", dbs[1].ExecCount(), dbs[2].QueryCount())
+	}
+
+	r, err := sess.Query(ctx, "select id from tbl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for r.Next() {
+		var id int64
+		if err := r.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	_ = r.Close()
+	if len(ids) != 3 || ids[0] != 0 || ids[2] != 2 {
+		t.Fatal("expect merged results of all shards ", ids)
+	}
+
+	if _, err := sess.Execute(ctx, "delete from tbl"); !stderrors.Is(err, errors.ShardKeyNotFound) {
+		t.Fatal("expect ShardKeyNotFound but get ", err)
+	}
+
+	if err := sess.Begin(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.Execute(ctx, "update tbl set name = ? where id = ?", "y", 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.Execute(ctx, "update tbl set name = ? where id = ?", "y", 110); !stderrors.Is(err, errors.ShardCrossTransaction) {
+		t.Fatal("expect ShardCrossTransaction but get ", err)
+	}
+	if err := sess.Rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if dbs[0].Begins != 1 || dbs[0].Rollbacks != 1 || dbs[1].Begins != 0 {
+		t.Fatal("expect transaction only on shard 0 ", dbs[0].Begins, dbs[0].Rollbacks, dbs[1].Begins)
+	}
+
+	ret, err := sess.ExecuteBatch(ctx, "insert into tbl (name, id) values (?, ?)", [][]interface{}{{"a", 1}, {"b", 101}, {"c", 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := ret.RowsAffected(); n != 3 || dbs[0].ExecCount() != 3 || dbs[1].ExecCount() != 2 {
+		t.Fatal("expect batch grouped by shard ", n, dbs[0].ExecCount(), dbs[1].ExecCount())
+	}
+
+	dbs[1].ExecErrorArg = "e"
+	ret, err = sess.ExecuteBatch(ctx, "insert into tbl (name, id) values (?, ?)", [][]interface{}{{"d", 1}, {"e", 101}, {"f", 201}, {"g", 2}})
+	if err == nil || ret == nil {
+		t.Fatal("expect result with error but get ", ret, err)
+	}
+	if ret.Items[0].Err != nil || ret.Items[3].Err != nil || ret.Items[1].Err == nil {
+		t.Fatal("expect items of shard 0 committed and shard 1 failed ", ret.Items)
+	}
+	if !stderrors.Is(ret.Items[2].Err, errors.ShardBatchAborted) || dbs[2].ExecCount() != 0 {
+		t.Fatal("expect items of shard 2 not executed ", ret.Items, dbs[2].ExecCount())
+	}
+
+	dbs[0].CommitError = true
+	if err := sess.Begin(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.Execute(ctx, "update tbl set name = ? where id = ?", "z", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.Commit(ctx); err == nil {
+		t.Fatal("expect commit error")
+	}
+	if _, err := sess.Execute(ctx, "update tbl set name = ? where id = ?", "z", 110); !stderrors.Is(err, errors.ShardCrossTransaction) {
+		t.Fatal("expect transaction kept after failed commit but get ", err)
+	}
+	if err := sess.Rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.Execute(ctx, "update tbl set name = ? where id = ?", "z", 11); err != nil {
+		t.Fatal(err)
+	}
+	if dbs[0].Execs[len(dbs[0].Execs)-1].InTx {
+		t.Fatal("expect exec outside the failed transaction")
+	}
+}
+
+func TestConnectionManager(t *testing.T) {
+	orders, odsn := newFakeDB("orders")
+	_, udsn := newFakeDB("users")
+	m := connection.NewManager(
+		connection.ManagerOpts.AddConnection("orders", sqldrv.NewSqlConnection(FakeDriverName, odsn)))
+	if err := m.Register("users", sqldrv.NewSqlConnection(FakeDriverName, udsn)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Register("users", sqldrv.NewSqlConnection(FakeDriverName, udsn)); err == nil {
+		t.Fatal("expect duplicate error")
+	}
+	if err := m.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := m.GetSession("unknown"); !stderrors.Is(err, errors.ConnectionNotFound) {
+		t.Fatal("expect ConnectionNotFound but get ", err)
+	}
+	sess, err := m.GetSession("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.Execute(ctx, "update orders set state = ?", 1); err != nil {
+		t.Fatal(err)
+	}
+	if orders.ExecCount() != 1 {
+		t.Fatal("expect exec on orders")
+	}
+
+	orders.Down = true
+	if h := m.Ping(ctx); h["orders"] || !h["users"] {
+		t.Fatal("unexpected health ", h)
+	}
+	orders.Down = false
+
+	closed := make(chan error)
+	go func() {
+		closed <- m.Close(ctx)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-closed:
+		t.Fatal("expect waiting for in-flight session")
+	default:
+	}
+	if _, err := m.GetSession("users"); err != errors.ConnectionClosed {
+		t.Fatal("expect ConnectionClosed but get ", err)
+	}
+	_ = sess.Close()
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+
+	m2 := connection.NewManager(connection.ManagerOpts.AddConnection("orders", sqldrv.NewSqlConnection(FakeDriverName, odsn)))
+	if err := m2.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m2.GetSession("orders"); err != nil {
+		t.Fatal(err)
+	}
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := m2.Close(tctx); !stderrors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expect drain timeout but get ", err)
+	}
+}
+
+func TestSqlStatementTimeout(t *testing.T) {
+	db, dsn := newFakeDB("statement_timeout")
+	db.Columns = []string{"id"}
+	db.Rows = [][]driver.Value{{int64(1)}, {int64(2)}}
+	conn := sqldrv.NewSqlConnection(FakeDriverName, dsn, sqldrv.ConnOpts.SetStatementTimeout(20*time.Millisecond))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	sess, err := conn.GetSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+
+	ctx := context.Background()
+	r, err := sess.Query(ctx, "select id from tbl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for r.Next() {
+		n++
+	}
+	r.Close()
+	if n != 2 {
+		t.Fatal("expect 2 rows but get ", n)
+	}
+
+	db.Delay = 50 * time.Millisecond
+	_, err = sess.Execute(ctx, "update tbl set name = ?", "a")
+	if !stderrors.Is(err, errors.StatementTimeout) {
+		t.Fatal("expect timeout but get ", err)
+	}
+	_, err = sess.Query(ctx, "select id from tbl")
+	if !stderrors.Is(err, errors.StatementTimeout) {
+		t.Fatal("expect timeout but get ", err)
+	}
+	if _, err := sess.Execute(statement.WithTimeout(ctx, 0), "update tbl set name = ?", "b"); err != nil {
+		t.Fatal("expect default timeout disabled but get ", err)
+	}
+	dctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if _, err := sess.Execute(dctx, "update tbl set name = ?", "c"); err != nil {
+		t.Fatal("expect deadline of ctx used but get ", err)
+	}
+	_, err = sess.Execute(statement.WithTimeout(ctx, 10*time.Millisecond), "update tbl set name = ?", "d")
+	if !stderrors.Is(err, errors.StatementTimeout) {
+		t.Fatal("expect timeout but get ", err)
+	}
+	if db.ExecCount() != 2 {
+		t.Fatal("expect 2 execs but get ", db.ExecCount())
+	}
+}
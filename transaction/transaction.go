@@ -41,3 +41,6 @@ type Transaction interface {
 
 	Rollback(ctx context.Context, successCallback func(handler.Handler) error) error
 }
+
+// Wrapper wraps the transaction created by session, e.g. to intercept its methods.
+type Wrapper func(tx Transaction) Transaction